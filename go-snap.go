@@ -2,6 +2,7 @@ package main
 
 import (
 	"os"
+	"runtime"
 	"strings"
 
 	"github.com/desal/go-snap/snapshot"
@@ -10,20 +11,119 @@ import (
 	"github.com/jawher/mow.cli"
 )
 
-func setupContext(format richtext.Format, verbose, veryVerbose bool) *snapshot.Context {
+var (
+	//config is the project configuration, loaded before any command runs.
+	config snapshot.Config
+
+	//filenameGiven is true if the snapshot filename was given with -f.
+	filenameGiven bool
+)
+
+func setupContext(format richtext.Format, verbose, veryVerbose bool, options ...snapshot.Option) *snapshot.Context {
 	goPath, err := gocmd.EnvGoPath()
 	if err != nil {
 		format.ErrorLine("Failed to get GOPATH: %s", err.Error())
 		os.Exit(1)
 	}
-	var flags []snapshot.Flag
+	//Flags come after the configuration so they take precedence
+	options = append([]snapshot.Option{config}, options...)
+
 	if veryVerbose {
-		flags = append(flags, snapshot.Verbose, snapshot.CmdVerbose)
+		options = append(options, snapshot.Verbose, snapshot.CmdVerbose)
 	} else if verbose {
-		flags = append(flags, snapshot.Verbose)
+		options = append(options, snapshot.Verbose)
+	}
+
+	return snapshot.New(format, goPath, options...)
+}
+
+//configPackages is pkgs as a go list argument, or the configured packages if
+//none are given.
+func configPackages(format richtext.Format, pkgs []string) string {
+	if len(pkgs) == 0 {
+		pkgs = config.Packages
+	}
+	if len(pkgs) == 0 {
+		format.ErrorLine("No packages given, and none configured in %s", snapshot.ConfigFilename)
+		os.Exit(1)
+	}
+	return strings.Join(pkgs, " ")
+}
+
+//configTagSets is tagSets, or the configured tag sets if none are given.
+func configTagSets(tagSets []string) []string {
+	if len(tagSets) == 0 {
+		tagSets = config.TagSets
+	}
+	if len(tagSets) == 0 {
+		tagSets = []string{""}
+	}
+	return tagSets
+}
+
+//platforms is the Platforms option for the --platform lists, nil if there
+//are none so any configured are used.
+func platforms(format richtext.Format, lists []string) []snapshot.Option {
+	if len(lists) == 0 {
+		return nil
+	}
+	r, err := snapshot.ParsePlatforms(lists)
+	if err != nil {
+		format.ErrorLine("%s", err.Error())
+		os.Exit(1)
+	}
+	return []snapshot.Option{r}
+}
+
+//selectBuilds is depsFile with only the dependencies required by the
+//--tags and --platform selection.
+func selectBuilds(format richtext.Format, depsFile snapshot.DepsFile, tagSets, platformLists []string) snapshot.DepsFile {
+	platforms, err := snapshot.ParsePlatforms(platformLists)
+	if err == nil {
+		depsFile, err = snapshot.SelectBuilds(depsFile, tagSets, platforms)
+	}
+	if err != nil {
+		format.ErrorLine("%s", err.Error())
+		os.Exit(1)
 	}
+	return depsFile
+}
 
-	return snapshot.New(format, goPath, flags...)
+//doTests is false if test dependencies are skipped by flag or configuration,
+//unless withTests overrides the configuration.
+func doTests(skipTests, withTests bool) bool {
+	if withTests {
+		return true
+	}
+	return !skipTests && !config.SkipTests
+}
+
+//runReproduce reproduces depsFile, or with dryRun only prints what would be
+//done, as JSON if asJson is set. It returns the result, nil for a dry run,
+//and whether everything succeeded.
+func runReproduce(format richtext.Format, ctx *snapshot.Context, depsFile snapshot.DepsFile, doTests bool, alreadyExists snapshot.AlreadyExists, dryRun, asJson bool) ([]snapshot.ReproducePkg, bool) {
+	if dryRun {
+		plan := ctx.Plan(".", depsFile, doTests, alreadyExists)
+		if asJson {
+			if err := snapshot.WritePlanJson("stdout", plan); err != nil {
+				format.ErrorLine("Could not write plan: %s", err.Error())
+				os.Exit(1)
+			}
+		} else {
+			ctx.PrintPlan(plan)
+		}
+
+		for _, planPkg := range plan {
+			if planPkg.Action == snapshot.Action_Fail {
+				return nil, false
+			}
+		}
+		return nil, true
+	}
+
+	result, err := ctx.Reproduce(".", depsFile, doTests, alreadyExists)
+	ctx.PrintReproduceSummary(result)
+	return result, err == nil
 }
 
 func main() {
@@ -31,44 +131,103 @@ func main() {
 	format := richtext.New()
 
 	var (
-		filename    = app.StringOpt("f filename", "snapshot.json", "filename to save snapshot to")
+		filename    = app.StringOpt("f filename", "", "filename to save snapshot to, snapshot.json unless configured")
 		verbose     = app.BoolOpt("v verbose", false, "Verbose output")
 		veryVerbose = app.BoolOpt("vv veryverbose", false, "Verbose output and verbose command output")
 	)
+
+	app.Before = func() {
+		var err error
+		config, err = snapshot.LoadConfig(".")
+		if err != nil {
+			format.ErrorLine("Could not read %s: %s", snapshot.ConfigFilename, err.Error())
+			os.Exit(1)
+		}
+
+		filenameGiven = *filename != ""
+		if *filename == "" {
+			*filename = config.Filename
+		}
+		if *filename == "" {
+			*filename = "snapshot.json"
+		}
+	}
 	app.Command("snapshot", "Takes a snapshot of all currently used dependencies", func(c *cli.Cmd) {
-		c.Spec = "[--tags...] PKG..."
+		c.Spec = "[-j] [--format] [--tags...] [--platform...] [--ignore...] [--allow-dirty...] [--graph] [PKG...]"
 		var (
-			tagSets = c.StringsOpt("tags", nil, "capture with tags (can be repeated)")
-			pkgs    = c.StringsArg("PKG", nil, "Packages to snapshot")
+			jobs       = c.IntOpt("j jobs", runtime.NumCPU(), "number of dependencies to scan concurrently")
+			lockFormat = c.StringOpt("format", "json", "format to save in (json, gomod, godeps, glide, dep or govendor), other than json to its own lock file unless -f is given")
+			tagSets    = c.StringsOpt("tags", nil, "capture with tags (can be repeated)")
+			platform   = c.StringsOpt("platform", nil, "capture for GOOS/GOARCH pairs, such as linux/amd64,windows/amd64 (can be repeated)")
+			ignore     = c.StringsOpt("ignore", nil, "leave out deps matching an import path glob, such as github.com/desal/..., in place of any configured (can be repeated)")
+			allowDirty = c.StringsOpt("allow-dirty", nil, "record deps matching an import path glob even with local changes, in place of any configured (can be repeated)")
+			withGraph  = c.BoolOpt("graph", false, "Record the import graph between repositories, see graph")
+			pkgs       = c.StringsArg("PKG", nil, "Packages to snapshot, as configured by default")
 		)
 
 		c.Action = func() {
-			if len(*tagSets) == 0 {
-				*tagSets = append(*tagSets, "")
+			//Other formats never replace the snapshot, which every other
+			//command reads
+			lockFileFormat := snapshot.LockFormat(*lockFormat)
+			lockFilename, err := snapshot.LockFilename(lockFileFormat)
+			if err != nil {
+				format.ErrorLine("%s", err.Error())
+				os.Exit(1)
+			} else if lockFileFormat == snapshot.LockFormat_Json || filenameGiven {
+				lockFilename = *filename
+			}
+
+			options := []snapshot.Option{snapshot.Jobs(*jobs), snapshot.Ignore(*ignore), snapshot.AllowDirty(*allowDirty)}
+			if *withGraph {
+				options = append(options, snapshot.ImportGraph)
 			}
-			ctx := setupContext(format, *verbose, *veryVerbose)
-			depsFile, err := ctx.Snapshot(".", strings.Join(*pkgs, " "), *tagSets)
+			ctx := setupContext(format, *verbose, *veryVerbose, append(options, platforms(format, *platform)...)...)
+			depsFile, err := ctx.Snapshot(".", configPackages(format, *pkgs), configTagSets(*tagSets))
 
 			if err != nil {
 				format.ErrorLine("%s", err.Error())
 			}
 
-			err = snapshot.WriteJson(*filename, depsFile)
+			err = snapshot.Export(lockFilename, depsFile, lockFileFormat)
 			if err != nil {
-				format.ErrorLine("Could not write snapshot '%s': %s", *filename, err.Error())
+				format.ErrorLine("Could not write snapshot '%s': %s", lockFilename, err.Error())
 				os.Exit(1)
 			}
 		}
 	})
 
-	app.Command("update", "Updates all deps specified in file to latest version found in git", func(c *cli.Cmd) {
-		c.Spec = "[-t]"
+	app.Command("update", "Updates deps specified in file to latest version found in git", func(c *cli.Cmd) {
+		c.Spec = "[-j] [-t | --tests] [--atomic] [--patch | --minor | --major | --latest-tag] [--dry-run [--json] | --no-write] [PKG...]"
 		var (
+			jobs      = c.IntOpt("j jobs", runtime.NumCPU(), "number of dependencies to update concurrently")
 			skipTests = c.BoolOpt("t notests", false, "Skip dependencies used exclusively for tests")
+			withTests = c.BoolOpt("tests", false, "Include dependencies used exclusively for tests, even if configured not to")
+			atomic    = c.BoolOpt("atomic", false, "Roll back every dependency if any fails")
+			patch     = c.BoolOpt("patch", false, "Update to the highest tag with the same minor version")
+			minor     = c.BoolOpt("minor", false, "Update to the highest tag with the same major version")
+			major     = c.BoolOpt("major", false, "Update to the highest newer tag")
+			latestTag = c.BoolOpt("latest-tag", false, "Check out the highest tag, even if not newer")
+			dryRun    = c.BoolOpt("dry-run", false, "Show what would be done without changing anything")
+			asJson    = c.BoolOpt("json", false, "Show the dry run as JSON")
+			noWrite   = c.BoolOpt("no-write", false, "Leave the snapshot file as it is")
+			pkgs      = c.StringsArg("PKG", nil, "Import paths or globs such as github.com/desal/... to update, all by default")
 		)
 
 		c.Action = func() {
-			ctx := setupContext(format, *verbose, *veryVerbose)
+			options := []snapshot.Option{snapshot.Jobs(*jobs)}
+			if *atomic {
+				options = append(options, snapshot.Atomic)
+			}
+			if *patch {
+				options = append(options, snapshot.Semver_Patch)
+			} else if *minor {
+				options = append(options, snapshot.Semver_Minor)
+			} else if *major {
+				options = append(options, snapshot.Semver_Major)
+			} else if *latestTag {
+				options = append(options, snapshot.Semver_Latest)
+			}
+			ctx := setupContext(format, *verbose, *veryVerbose, options...)
 
 			depsFile, err := snapshot.ReadJson(*filename)
 			if err != nil {
@@ -76,25 +235,62 @@ func main() {
 				os.Exit(1)
 			}
 
-			err = ctx.Reproduce(".", depsFile, !*skipTests, snapshot.AlreadyExists_UpdateLatest)
-			if err != nil {
-				format.ErrorLine("%s", err.Error())
+			selected := depsFile
+			if len(*pkgs) != 0 {
+				selected, err = snapshot.SelectDeps(depsFile, *pkgs)
+				if err != nil {
+					format.ErrorLine("%s", err.Error())
+					os.Exit(1)
+				}
+			}
+
+			result, ok := runReproduce(format, ctx, selected, doTests(*skipTests, *withTests), snapshot.AlreadyExists_UpdateLatest, *dryRun, *asJson)
+
+			//Unselected dependencies stay pinned, updated ones are recorded as
+			//they now are, including any new tags at the same revision
+			if !*dryRun {
+				ctx.PrintChanges(snapshot.Changes(depsFile, result))
+
+				updated := false
+				for _, reproducePkg := range result {
+					updated = updated || reproducePkg.Updated != nil
+				}
+				if updated && !*noWrite {
+					err = snapshot.WriteJson(*filename, snapshot.UpdateDepsFile(depsFile, result))
+					if err != nil {
+						format.ErrorLine("Could not write snapshot '%s': %s", *filename, err.Error())
+						os.Exit(1)
+					}
+				}
+			}
+			if !ok {
 				os.Exit(1)
 			}
 		}
 	})
 
 	app.Command("reproduce", "Reproduces environment from file", func(c *cli.Cmd) {
-		c.Spec = "[-t] [-f | -i | -c]"
+		c.Spec = "[-j] [-t | --tests] [--tags...] [--platform...] [-f | -i | -c] [--atomic] [--dry-run [--json]]"
 		var (
+			jobs      = c.IntOpt("j jobs", runtime.NumCPU(), "number of dependencies to reproduce concurrently")
 			skipTests = c.BoolOpt("t notests", false, "Skip dependencies used exclusively for tests")
+			withTests = c.BoolOpt("tests", false, "Include dependencies used exclusively for tests, even if configured not to")
+			tagSets   = c.StringsOpt("tags", nil, "only deps required with a snapshotted tag set (can be repeated)")
+			platform  = c.StringsOpt("platform", nil, "only deps required on snapshotted GOOS/GOARCH pairs (can be repeated)")
+			atomic    = c.BoolOpt("atomic", false, "Roll back every dependency if any fails")
 			force     = c.BoolOpt("f force", false, "Force dependencies to version, even if they exist")
 			ignore    = c.BoolOpt("i ignore", false, "Continue if an existing dependency is found")
 			check     = c.BoolOpt("c check", false, "If an existing dependency is found, check it against file")
+			dryRun    = c.BoolOpt("dry-run", false, "Show what would be done without changing anything")
+			asJson    = c.BoolOpt("json", false, "Show the dry run as JSON")
 		)
 
 		c.Action = func() {
-			ctx := setupContext(format, *verbose, *veryVerbose)
+			options := []snapshot.Option{snapshot.Jobs(*jobs)}
+			if *atomic {
+				options = append(options, snapshot.Atomic)
+			}
+			ctx := setupContext(format, *verbose, *veryVerbose, options...)
 
 			depsFile, err := snapshot.ReadJson(*filename)
 			if err != nil {
@@ -111,29 +307,98 @@ func main() {
 				alreadyExists = snapshot.AlreadyExists_Check
 			}
 
-			err = ctx.Reproduce(".", depsFile, !*skipTests, alreadyExists)
+			depsFile = selectBuilds(format, depsFile, *tagSets, *platform)
+			if _, ok := runReproduce(format, ctx, depsFile, doTests(*skipTests, *withTests), alreadyExists, *dryRun, *asJson); !ok {
+				os.Exit(1)
+			}
+		}
+	})
+
+	app.Command("vendor", "Reproduces environment from file into vendor/ as plain files", func(c *cli.Cmd) {
+		c.Spec = "[-j] [-t | --tests] [--tags...] [--platform...] [--strip-tests] [--strip-assets] [--dry-run [--json]]"
+		var (
+			jobs        = c.IntOpt("j jobs", runtime.NumCPU(), "number of dependencies to vendor concurrently")
+			skipTests   = c.BoolOpt("t notests", false, "Skip dependencies used exclusively for tests")
+			withTests   = c.BoolOpt("tests", false, "Include dependencies used exclusively for tests, even if configured not to")
+			tagSets     = c.StringsOpt("tags", nil, "only deps required with a snapshotted tag set (can be repeated)")
+			platform    = c.StringsOpt("platform", nil, "only deps required on snapshotted GOOS/GOARCH pairs (can be repeated)")
+			stripTests  = c.BoolOpt("strip-tests", false, "Leave out _test.go files and testdata directories")
+			stripAssets = c.BoolOpt("strip-assets", false, "Leave out everything but .go files and licenses")
+			dryRun      = c.BoolOpt("dry-run", false, "Show what would be done without changing anything")
+			asJson      = c.BoolOpt("json", false, "Show the dry run as JSON")
+		)
+
+		c.Action = func() {
+			options := []snapshot.Option{snapshot.Jobs(*jobs), snapshot.Vendor}
+			if *stripTests {
+				options = append(options, snapshot.StripTests)
+			}
+			if *stripAssets {
+				options = append(options, snapshot.StripAssets)
+			}
+			ctx := setupContext(format, *verbose, *veryVerbose, options...)
+
+			depsFile, err := snapshot.ReadJson(*filename)
 			if err != nil {
-				format.ErrorLine("%s", err.Error())
+				format.ErrorLine("Could not read snapshot '%s': %s", *filename, err.Error())
+				os.Exit(1)
+			}
+
+			depsFile = selectBuilds(format, depsFile, *tagSets, *platform)
+			if _, ok := runReproduce(format, ctx, depsFile, doTests(*skipTests, *withTests), snapshot.AlreadyExists_Force, *dryRun, *asJson); !ok {
 				os.Exit(1)
 			}
 		}
 	})
 
 	app.Command("compare", "Compares snapshot.json to what's currently used to build", func(c *cli.Cmd) {
-		c.Spec = "[--tags...] [-t] PKG..."
+		c.Spec = "[-j] [--tags...] [--platform...] [-t | --tests] [--ignore...] [--allow-dirty...] [--log] [--output] [--policy] [--severity...] [PKG...]"
 
 		var (
-			tagSets   = c.StringsOpt("tags", nil, "capture with tags (can be repeated)")
-			skipTests = c.BoolOpt("t notests", false, "Skip dependencies used exclusively for tests")
-			pkgs      = c.StringsArg("PKG", nil, "Packages to snapshot")
+			jobs       = c.IntOpt("j jobs", runtime.NumCPU(), "number of dependencies to scan concurrently")
+			tagSets    = c.StringsOpt("tags", nil, "capture with tags (can be repeated)")
+			platform   = c.StringsOpt("platform", nil, "capture for GOOS/GOARCH pairs, such as linux/amd64,windows/amd64 (can be repeated)")
+			skipTests  = c.BoolOpt("t notests", false, "Skip dependencies used exclusively for tests")
+			withTests  = c.BoolOpt("tests", false, "Include dependencies used exclusively for tests, even if configured not to")
+			ignore     = c.StringsOpt("ignore", nil, "leave out deps matching an import path glob, such as github.com/desal/..., in place of any configured (can be repeated)")
+			allowDirty = c.StringsOpt("allow-dirty", nil, "accept deps matching an import path glob even with local changes, in place of any configured (can be repeated)")
+			withLog    = c.BoolOpt("log", false, "List the commits between pinned and actual revisions")
+			output     = c.StringOpt("output", "text", "format to show the results in (text, json, junit or tap)")
+			policy     = c.StringOpt("policy", "", "file setting the severity of each kind of difference")
+			severity   = c.StringsOpt("severity", nil, "severity of a kind of difference, such as newer=ok, over any configured (can be repeated)")
+			pkgs       = c.StringsArg("PKG", nil, "Packages to snapshot, as configured by default")
 		)
 
 		c.Action = func() {
-			if len(*tagSets) == 0 {
-				*tagSets = append(*tagSets, "")
+			switch snapshot.CompareOutput(*output) {
+			case "text", snapshot.CompareOutput_Json, snapshot.CompareOutput_JUnit, snapshot.CompareOutput_Tap:
+			default:
+				format.ErrorLine("Unknown output format '%s', expected text, json, junit or tap", *output)
+				os.Exit(1)
 			}
 
-			ctx := setupContext(format, *verbose, *veryVerbose)
+			options := []snapshot.Option{snapshot.Jobs(*jobs), snapshot.Ignore(*ignore), snapshot.AllowDirty(*allowDirty)}
+			options = append(options, platforms(format, *platform)...)
+			if *withLog {
+				options = append(options, snapshot.Log)
+			}
+
+			comparePolicy := snapshot.Policy{}
+			if *policy != "" {
+				filePolicy, err := snapshot.ReadPolicy(*policy)
+				if err != nil {
+					format.ErrorLine("Could not read policy '%s': %s", *policy, err.Error())
+					os.Exit(1)
+				}
+				comparePolicy = filePolicy
+			}
+			flagPolicy, err := snapshot.ParsePolicySettings(*severity)
+			if err != nil {
+				format.ErrorLine("%s", err.Error())
+				os.Exit(1)
+			}
+			options = append(options, comparePolicy.Merge(flagPolicy))
+			ctx := setupContext(format, *verbose, *veryVerbose, options...)
 
 			depsFile, err := snapshot.ReadJson(*filename)
 			if err != nil {
@@ -141,13 +406,217 @@ func main() {
 				os.Exit(1)
 			}
 
-			_, ok := ctx.Compare(".", strings.Join(*pkgs, " "), *tagSets, depsFile, !*skipTests)
-			if !ok {
+			renderer := snapshot.NewTableRenderer(format)
+			if *output != "text" {
+				renderer = snapshot.NewWriteRenderer("stdout", snapshot.CompareOutput(*output))
+			}
+
+			report := ctx.Report(".", configPackages(format, *pkgs), configTagSets(*tagSets), depsFile, doTests(*skipTests, *withTests))
+			if err := renderer.Render(report); err != nil {
+				format.ErrorLine("%s", err.Error())
+				os.Exit(1)
+			}
+			if !report.Ok() {
 				os.Exit(1)
 			}
 			os.Exit(0)
 		}
 
 	})
+
+	app.Command("diff", "Compares two snapshot files without scanning", func(c *cli.Cmd) {
+		c.Spec = "[-j] [--log] [--json] OLD NEW"
+		var (
+			jobs    = c.IntOpt("j jobs", runtime.NumCPU(), "number of commit logs to read concurrently")
+			withLog = c.BoolOpt("log", false, "List the commits between the two revisions from GOPATH")
+			asJson  = c.BoolOpt("json", false, "Show the differences as JSON")
+			oldFile = c.StringArg("OLD", "", "snapshot file to compare from")
+			newFile = c.StringArg("NEW", "", "snapshot file to compare to")
+		)
+
+		c.Action = func() {
+			ctx := setupContext(format, *verbose, *veryVerbose, snapshot.Jobs(*jobs))
+
+			oldDepsFile, err := snapshot.ReadJson(*oldFile)
+			if err != nil {
+				format.ErrorLine("Could not read snapshot '%s': %s", *oldFile, err.Error())
+				os.Exit(1)
+			}
+			newDepsFile, err := snapshot.ReadJson(*newFile)
+			if err != nil {
+				format.ErrorLine("Could not read snapshot '%s': %s", *newFile, err.Error())
+				os.Exit(1)
+			}
+
+			diff := snapshot.DiffDepsFiles(oldDepsFile, newDepsFile)
+			if *withLog {
+				ctx.AddLogs(&diff)
+			}
+			if *asJson {
+				if err := snapshot.WriteDiffJson("stdout", diff); err != nil {
+					format.ErrorLine("Could not write differences: %s", err.Error())
+					os.Exit(1)
+				}
+			} else {
+				ctx.PrintDiff(diff)
+			}
+		}
+	})
+
+	app.Command("verify", "Checks the content hashes in snapshot.json against GOPATH or vendor/", func(c *cli.Cmd) {
+		c.Spec = "[-j] [-t | --tests] [--vendor [--strip-tests] [--strip-assets]]"
+		var (
+			jobs        = c.IntOpt("j jobs", runtime.NumCPU(), "number of dependencies to verify concurrently")
+			skipTests   = c.BoolOpt("t notests", false, "Skip dependencies used exclusively for tests")
+			withTests   = c.BoolOpt("tests", false, "Include dependencies used exclusively for tests, even if configured not to")
+			vendor      = c.BoolOpt("vendor", false, "Verify vendor/ instead of GOPATH")
+			stripTests  = c.BoolOpt("strip-tests", false, "vendor/ was made with vendor --strip-tests")
+			stripAssets = c.BoolOpt("strip-assets", false, "vendor/ was made with vendor --strip-assets")
+		)
+
+		c.Action = func() {
+			options := []snapshot.Option{snapshot.Jobs(*jobs)}
+			if *vendor {
+				options = append(options, snapshot.Vendor)
+			}
+			if *stripTests {
+				options = append(options, snapshot.StripTests)
+			}
+			if *stripAssets {
+				options = append(options, snapshot.StripAssets)
+			}
+			ctx := setupContext(format, *verbose, *veryVerbose, options...)
+
+			depsFile, err := snapshot.ReadJson(*filename)
+			if err != nil {
+				format.ErrorLine("Could not read snapshot '%s': %s", *filename, err.Error())
+				os.Exit(1)
+			}
+
+			_, ok := ctx.Verify(".", depsFile, doTests(*skipTests, *withTests))
+			if !ok {
+				os.Exit(1)
+			}
+		}
+	})
+
+	app.Command("outdated", "Reports how far each dep in snapshot.json is behind its remote", func(c *cli.Cmd) {
+		c.Spec = "[-j] [-t | --tests] [--offline] [--json]"
+		var (
+			jobs      = c.IntOpt("j jobs", runtime.NumCPU(), "number of dependencies to check concurrently")
+			skipTests = c.BoolOpt("t notests", false, "Skip dependencies used exclusively for tests")
+			withTests = c.BoolOpt("tests", false, "Include dependencies used exclusively for tests, even if configured not to")
+			offline   = c.BoolOpt("offline", false, "Use the checkouts in GOPATH without fetching")
+			asJson    = c.BoolOpt("json", false, "Show the report as JSON")
+		)
+
+		c.Action = func() {
+			options := []snapshot.Option{snapshot.Jobs(*jobs)}
+			if *offline {
+				options = append(options, snapshot.Offline)
+			}
+			ctx := setupContext(format, *verbose, *veryVerbose, options...)
+
+			depsFile, err := snapshot.ReadJson(*filename)
+			if err != nil {
+				format.ErrorLine("Could not read snapshot '%s': %s", *filename, err.Error())
+				os.Exit(1)
+			}
+
+			result, ok := ctx.Outdated(depsFile, doTests(*skipTests, *withTests))
+			if *asJson {
+				if err := snapshot.WriteOutdatedJson("stdout", result); err != nil {
+					format.ErrorLine("Could not write report: %s", err.Error())
+					os.Exit(1)
+				}
+			} else {
+				ctx.PrintOutdated(result)
+			}
+			if !ok {
+				os.Exit(1)
+			}
+		}
+	})
+
+	app.Command("import", "Converts a Godeps.json, glide.lock, Gopkg.lock or vendor.json into a snapshot", func(c *cli.Cmd) {
+		c.Spec = "[--format] FILE"
+		var (
+			lockFormat = c.StringOpt("format", "", "format of FILE (godeps, glide, dep or govendor), guessed from its name by default")
+			lockFile   = c.StringArg("FILE", "", "lock file to import")
+		)
+
+		c.Action = func() {
+			var err error
+			lockFileFormat := snapshot.LockFormat(*lockFormat)
+			if lockFileFormat == "" {
+				lockFileFormat, err = snapshot.LockFormatOf(*lockFile)
+				if err != nil {
+					format.ErrorLine("%s", err.Error())
+					os.Exit(1)
+				}
+			}
+
+			depsFile, err := snapshot.Import(*lockFile, lockFileFormat)
+			if err != nil {
+				format.ErrorLine("Could not import '%s': %s", *lockFile, err.Error())
+				os.Exit(1)
+			}
+
+			err = snapshot.WriteJson(*filename, depsFile)
+			if err != nil {
+				format.ErrorLine("Could not write snapshot '%s': %s", *filename, err.Error())
+				os.Exit(1)
+			}
+		}
+	})
+
+	app.Command("export", "Exports snapshot.json to another format", func(c *cli.Cmd) {
+		c.Spec = "[-o] [FORMAT]"
+		var (
+			output     = c.StringOpt("o output", "stdout", "filename to write to")
+			lockFormat = c.StringArg("FORMAT", "gomod", "format to export (json, gomod, godeps, glide, dep or govendor)")
+		)
+
+		c.Action = func() {
+			depsFile, err := snapshot.ReadJson(*filename)
+			if err != nil {
+				format.ErrorLine("Could not read snapshot '%s': %s", *filename, err.Error())
+				os.Exit(1)
+			}
+
+			err = snapshot.Export(*output, depsFile, snapshot.LockFormat(*lockFormat))
+			if err != nil {
+				format.ErrorLine("%s", err.Error())
+				os.Exit(1)
+			}
+		}
+	})
+
+	app.Command("graph", "Shows the import graph recorded in snapshot.json by snapshot --graph", func(c *cli.Cmd) {
+		c.Spec = "[-o] [FORMAT]"
+		var (
+			output      = c.StringOpt("o output", "stdout", "filename to write to")
+			graphFormat = c.StringArg("FORMAT", "tree", "format to show the graph in (tree, dot or json)")
+		)
+
+		c.Action = func() {
+			depsFile, err := snapshot.ReadJson(*filename)
+			if err != nil {
+				format.ErrorLine("Could not read snapshot '%s': %s", *filename, err.Error())
+				os.Exit(1)
+			}
+			if depsFile.Graph == nil {
+				format.ErrorLine("Snapshot '%s' has no import graph, take it with snapshot --graph", *filename)
+				os.Exit(1)
+			}
+
+			err = snapshot.WriteGraph(*output, *depsFile.Graph, snapshot.GraphFormat(*graphFormat))
+			if err != nil {
+				format.ErrorLine("%s", err.Error())
+				os.Exit(1)
+			}
+		}
+	})
+
 	app.Run(os.Args)
 }
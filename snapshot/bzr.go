@@ -0,0 +1,139 @@
+package snapshot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/desal/git"
+)
+
+//bzrVCS is Bazaar, where revisions are revision ids rather than the revision
+//numbers, which differ between branches. Each bzr branch is its own
+//checkout, so there are no branches to choose between.
+type bzrVCS struct {
+	c *Context
+}
+
+func (v *bzrVCS) execf(dir, format string, a ...interface{}) (string, error) {
+	return v.c.vcsExecf(dir, "bzr", format, a...)
+}
+
+func (v *bzrVCS) Name() string { return "bzr" }
+
+func (v *bzrVCS) IsRepo(dir string) bool {
+	_, err := v.execf(dir, "root")
+	return err == nil
+}
+
+func (v *bzrVCS) Status(dir, branch string) (git.Status, error) {
+	status, err := v.execf(dir, "status -S")
+	if err != nil {
+		return git.Clean, err
+	} else if status != "" {
+		return git.Uncommitted, nil
+	}
+	return git.Clean, nil
+}
+
+func (v *bzrVCS) Branch(dir string) (string, error) {
+	return "", nil
+}
+
+func (v *bzrVCS) DefaultBranch(dir string) (string, error) {
+	return "", nil
+}
+
+func (v *bzrVCS) TopLevel(dir string) (string, error) {
+	return v.execf(dir, "root")
+}
+
+func (v *bzrVCS) RemoteUrl(dir string) (string, error) {
+	return v.execf(dir, "config parent_location")
+}
+
+func (v *bzrVCS) Revision(dir string) (string, error) {
+	//2 someone@example.com-20170103102030-abcdef
+	output, err := v.execf(dir, "revision-info --tree")
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(output)
+	if len(fields) != 2 {
+		return "", fmt.Errorf("Unexpected bzr revision-info output '%s'", output)
+	}
+	return fields[1], nil
+}
+
+func (v *bzrVCS) CommitTime(dir string) (time.Time, error) {
+	revision, err := v.Revision(dir)
+	if err != nil {
+		return time.Time{}, err
+	}
+	output, err := v.execf(dir, "log -r 'revid:%s' --timezone=utc", revision)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		//timestamp: Tue 2017-01-03 10:20:30 +0000
+		if strings.HasPrefix(line, "timestamp: ") {
+			return time.Parse("Mon 2006-01-02 15:04:05 -0700", strings.TrimPrefix(line, "timestamp: "))
+		}
+	}
+	return time.Time{}, fmt.Errorf("No timestamp in bzr log for %s", revision)
+}
+
+func (v *bzrVCS) Tags(dir string) ([]string, error) {
+	revision, err := v.Revision(dir)
+	if err != nil {
+		return nil, err
+	}
+	revisions, err := v.TagRevisions(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	r := []string{}
+	for tag, tagRevision := range revisions {
+		if tagRevision == revision {
+			r = append(r, tag)
+		}
+	}
+	sort.Strings(r)
+	return r, nil
+}
+
+func (v *bzrVCS) TagRevisions(dir string) (map[string]string, error) {
+	output, err := v.execf(dir, "tags --show-ids")
+	if err != nil {
+		return nil, err
+	}
+
+	r := map[string]string{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 {
+			r[fields[0]] = fields[1]
+		}
+	}
+	return r, nil
+}
+
+func (v *bzrVCS) Clone(dir, remote string) error {
+	return v.c.cloneExecf(dir, "bzr", "branch -q '%s' '%s'", remote, dir)
+}
+
+func (v *bzrVCS) Checkout(dir, revision string) error {
+	_, err := v.execf(dir, "update -q -r 'revid:%s'", revision)
+	return err
+}
+
+func (v *bzrVCS) Pull(dir, branch string) error {
+	if _, err := v.execf(dir, "pull -q"); err != nil {
+		return err
+	}
+	_, err := v.execf(dir, "update -q")
+	return err
+}
@@ -1,20 +1,41 @@
 package snapshot
 
 import (
+	"encoding/json"
 	"fmt"
+	"reflect"
 	"sort"
-	"strings"
 	"time"
 
-	"github.com/desal/richtext"
+	"github.com/desal/git"
 )
 
+//CompareResult is the severity of a ComparePkg.
 type CompareResult int
 
+//go:generate stringer -type CompareCategory
+
+//CompareCategory is what kind of difference, if any, a ComparePkg is.
+type CompareCategory int
+
+//ComparePkg is the result of comparing a single dependency. Expected is nil
+//for a new dependency, Actual for one no longer required.
 type ComparePkg struct {
 	ImportPath    string
 	Message       string
 	CompareResult CompareResult
+	Category      CompareCategory
+	TestDep       bool
+	Expected      *PkgDep    `json:",omitempty"`
+	Actual        *PkgDep    `json:",omitempty"`
+	Log           *CommitLog `json:",omitempty"` //Only with the Log flag
+}
+
+//CompareReport is the result of comparing every dependency, sorted by import
+//path, with those used exclusively for tests kept apart.
+type CompareReport struct {
+	Deps     []ComparePkg
+	TestDeps []ComparePkg
 }
 
 type ComparePkgs []ComparePkg
@@ -29,96 +50,228 @@ const (
 	CompareResult_Error
 )
 
-func (c *Context) Compare(workingDir, pkgString string, tagSets []string, depsFile DepsFile, dotests bool) ([]ComparePkg, bool) {
-	snapshot, _ := c.Snapshot(workingDir, pkgString, tagSets)
+const (
+	CompareCategory_Match       CompareCategory = iota
+	CompareCategory_New                         //Used but not in the snapshot
+	CompareCategory_Removed                     //In the snapshot but no longer used
+	CompareCategory_Newer                       //At a later commit than the snapshot
+	CompareCategory_Older                       //At an earlier commit than the snapshot
+	CompareCategory_Moved                       //At another commit, no commit time to tell which way
+	CompareCategory_Retagged                    //At the same commit with different tags
+	CompareCategory_Dirty                       //Has uncommitted or unpushed changes
+	CompareCategory_Failed                      //Could not be scanned
+	CompareCategory_NotOnMaster                 //At the snapshot's commit, but behind the head of its branch
+)
 
-	result := []ComparePkg{}
-	ok := true
+var (
+	compareResultLabels = map[CompareResult]string{
+		CompareResult_Ok:    "ok",
+		CompareResult_Warn:  "warn",
+		CompareResult_Error: "error",
+	}
 
-	comparePkgList := func(expected, actual []PkgDep) {
-		depsMap := map[string]PkgDep{}
-		for _, dep := range actual {
-			depsMap[dep.ImportPath] = dep
-		}
+	compareCategoryLabels = map[CompareCategory]string{
+		CompareCategory_Match:       "match",
+		CompareCategory_New:         "new",
+		CompareCategory_Removed:     "removed",
+		CompareCategory_Newer:       "newer",
+		CompareCategory_Older:       "older",
+		CompareCategory_Moved:       "moved",
+		CompareCategory_Retagged:    "retagged",
+		CompareCategory_Dirty:       "dirty",
+		CompareCategory_Failed:      "failed",
+		CompareCategory_NotOnMaster: "not-on-master",
+	}
 
-		for _, expectedDep := range expected {
-			actualDep, hasActual := depsMap[expectedDep.ImportPath]
-			if !hasActual {
-				result = append(result, ComparePkg{expectedDep.ImportPath, "No longer requried", CompareResult_Warn})
-				continue
-			}
+	//Severity of each category unless a Policy says otherwise
+	defaultSeverities = map[CompareCategory]CompareResult{
+		CompareCategory_Match:       CompareResult_Ok,
+		CompareCategory_New:         CompareResult_Error,
+		CompareCategory_Removed:     CompareResult_Warn,
+		CompareCategory_Newer:       CompareResult_Error,
+		CompareCategory_Older:       CompareResult_Error,
+		CompareCategory_Moved:       CompareResult_Error,
+		CompareCategory_Retagged:    CompareResult_Ok,
+		CompareCategory_Dirty:       CompareResult_Error,
+		CompareCategory_Failed:      CompareResult_Error,
+		CompareCategory_NotOnMaster: CompareResult_Ok,
+	}
+)
 
-			if actualDep.Error != nil {
-				result = append(result, ComparePkg{expectedDep.ImportPath, actualDep.Error.Error(), CompareResult_Error})
-				ok = false
-			} else if actualDep.SHA != expectedDep.SHA {
-				actual := actualDep.SHA[0:6]
-				expected := expectedDep.SHA[0:6]
+func (a CompareResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(compareResultLabels[a])
+}
 
-				if len(actualDep.Tags) != 0 {
-					actual += fmt.Sprintf(" %v", actualDep.Tags)
-				}
-				if len(expectedDep.Tags) != 0 {
-					expected += fmt.Sprintf(" %v", expectedDep.Tags)
-				}
+func (a CompareCategory) MarshalJSON() ([]byte, error) {
+	return json.Marshal(compareCategoryLabels[a])
+}
 
-				nilTime := time.Time{}
-				if expectedDep.CommitTime != nilTime {
-					actual += fmt.Sprintf(" [%s]", actualDep.CommitTime.Format("2006-01-02 15:04:05"))
-					expected += fmt.Sprintf(" [%s]", expectedDep.CommitTime.Format("2006-01-02 15:04:05"))
-					if expectedDep.CommitTime.Sub(actualDep.CommitTime) > 0 {
-						actual += " OLDER"
-						expected += " NEWER"
-					} else {
-						actual += " NEWER"
-						expected += " OLDER"
-					}
-				}
+//comparePkg compares a single dependency as it was snapshotted to how it is
+//now, either of which may be nil.
+func comparePkg(expectedDep, actualDep *PkgDep, testDep bool) ComparePkg {
+	r := ComparePkg{TestDep: testDep, Expected: expectedDep, Actual: actualDep}
+	if expectedDep == nil {
+		r.ImportPath = actualDep.ImportPath
+		r.Category = CompareCategory_New
+		r.Message = "New dependency"
+		return r
+	}
+
+	r.ImportPath = expectedDep.ImportPath
+	if actualDep == nil {
+		r.Category = CompareCategory_Removed
+		r.Message = "No longer requried"
+		return r
+	}
+
+	if actualDep.Error != nil {
+		r.Category = CompareCategory_Failed
+		if _, isStatus := actualDep.Error.(StatusError); isStatus {
+			r.Category = CompareCategory_Dirty
+		}
+		r.Message = actualDep.Error.Error()
+	} else if actualDep.SHA != expectedDep.SHA || actualDep.moduleVersion() != expectedDep.moduleVersion() {
+		actual := actualDep.revision()
+		expected := expectedDep.revision()
+
+		if len(actualDep.Tags) != 0 {
+			actual += fmt.Sprintf(" %v", actualDep.Tags)
+		}
+		if len(expectedDep.Tags) != 0 {
+			expected += fmt.Sprintf(" %v", expectedDep.Tags)
+		}
 
-				result = append(result, ComparePkg{expectedDep.ImportPath, fmt.Sprintf("(expected) %s vs (actual) %s", expected, actual), CompareResult_Error})
-				ok = false
+		r.Category = CompareCategory_Moved
+		nilTime := time.Time{}
+		if expectedDep.CommitTime != nilTime {
+			actual += fmt.Sprintf(" [%s]", actualDep.CommitTime.Format("2006-01-02 15:04:05"))
+			expected += fmt.Sprintf(" [%s]", expectedDep.CommitTime.Format("2006-01-02 15:04:05"))
+			if expectedDep.CommitTime.Sub(actualDep.CommitTime) > 0 {
+				actual += " OLDER"
+				expected += " NEWER"
+				r.Category = CompareCategory_Older
 			} else {
-				result = append(result, ComparePkg{expectedDep.ImportPath, "", CompareResult_Ok})
+				actual += " NEWER"
+				expected += " OLDER"
+				r.Category = CompareCategory_Newer
 			}
-			delete(depsMap, expectedDep.ImportPath)
 		}
 
-		//Only remaining ones should be new dependencies
-		for importPath, _ := range depsMap {
-			result = append(result, ComparePkg{importPath, "New dependency", CompareResult_Error})
-			ok = false
+		r.Message = fmt.Sprintf("(expected) %s vs (actual) %s", expected, actual)
+	} else if !reflect.DeepEqual(actualDep.Tags, expectedDep.Tags) && (len(actualDep.Tags) != 0 || len(expectedDep.Tags) != 0) {
+		r.Category = CompareCategory_Retagged
+		r.Message = fmt.Sprintf("(expected) %v vs (actual) %v", expectedDep.Tags, actualDep.Tags)
+	}
+	return r
+}
+
+func comparePkgList(expected, actual []PkgDep, testDep bool) []ComparePkg {
+	r := []ComparePkg{}
+	depsMap := map[string]PkgDep{}
+	for _, dep := range actual {
+		depsMap[dep.ImportPath] = dep
+	}
+
+	for _, dep := range expected {
+		expectedDep := dep
+		if actualDep, hasActual := depsMap[expectedDep.ImportPath]; hasActual {
+			r = append(r, comparePkg(&expectedDep, &actualDep, testDep))
+		} else {
+			r = append(r, comparePkg(&expectedDep, nil, testDep))
 		}
+		delete(depsMap, expectedDep.ImportPath)
 	}
 
-	comparePkgList(depsFile.Deps, snapshot.Deps)
-	if dotests {
-		comparePkgList(depsFile.TestDeps, snapshot.TestDeps)
+	//Only remaining ones should be new dependencies
+	for importPath, _ := range depsMap {
+		actualDep := depsMap[importPath]
+		r = append(r, comparePkg(nil, &actualDep, testDep))
 	}
 
-	sort.Sort(ComparePkgs(result))
+	for i := range r {
+		r[i].CompareResult = Policy(nil).severity(r[i])
+	}
+	sort.Sort(ComparePkgs(r))
+	return r
+}
 
-	maxLen := 0
-	for _, comparePkg := range result {
-		if len(comparePkg.ImportPath) > maxLen {
-			maxLen = len(comparePkg.ImportPath)
+//CompareDepsFiles compares expected, a snapshot, to actual, usually a fresh
+//snapshot of the same packages, without scanning or printing anything.
+//Severities are the defaults, see WithPolicy.
+func CompareDepsFiles(expected, actual DepsFile, doTests bool) CompareReport {
+	r := CompareReport{Deps: comparePkgList(expected.Deps, actual.Deps, false), TestDeps: []ComparePkg{}}
+	if doTests {
+		r.TestDeps = comparePkgList(expected.TestDeps, actual.TestDeps, true)
+	}
+	return r
+}
+
+//All is every ComparePkg in the report, sorted by import path.
+func (r CompareReport) All() []ComparePkg {
+	all := append(append([]ComparePkg{}, r.Deps...), r.TestDeps...)
+	sort.Stable(ComparePkgs(all))
+	return all
+}
+
+//Ok is false if any dependency has CompareResult_Error.
+func (r CompareReport) Ok() bool {
+	for _, comparePkg := range r.All() {
+		if comparePkg.CompareResult == CompareResult_Error {
+			return false
 		}
 	}
+	return true
+}
 
-	padding := strings.Repeat(" ", maxLen)
+//Report snapshots pkgString and compares it to depsFile under the Context's
+//Policy, adding the commit log of each moved dependency with the Log flag.
+//Ignored dependencies are left out of both. The result is not printed, but
+//the snapshot taken reports problems as Snapshot does, exiting on them with
+//the MustExit flag.
+func (c *Context) Report(workingDir, pkgString string, tagSets []string, depsFile DepsFile, dotests bool) CompareReport {
+	snapshot, notHead, _ := c.snapshot(workingDir, pkgString, tagSets)
+	r := CompareDepsFiles(c.withoutIgnored(depsFile), snapshot, dotests)
 
-	green := c.format.MakePrintf(richtext.Green, richtext.None, richtext.Bold)
-	orange := c.format.MakePrintf(richtext.Orange, richtext.None, richtext.Bold)
-	red := c.format.MakePrintf(richtext.Red, richtext.None, richtext.Bold)
+	//Only a match can be behind the head, anything else says more
+	for _, list := range [][]ComparePkg{r.Deps, r.TestDeps} {
+		for i := range list {
+			if _, behind := notHead[list[i].ImportPath]; behind && list[i].Category == CompareCategory_Match {
+				list[i].Category = CompareCategory_NotOnMaster
+				list[i].Message = "Not at the head of " + branchLabel(list[i].Actual.Branch)
+			}
+		}
+	}
+	r = r.WithPolicy(c.policy)
 
-	richPrefix := map[CompareResult]func(){
-		CompareResult_Ok:    func() { green("[ OK ]") },
-		CompareResult_Warn:  func() { orange("[WARN]") },
-		CompareResult_Error: func() { red("[FAIL]") },
+	if c.flags.Checked(Log) {
+		for _, list := range [][]ComparePkg{r.Deps, r.TestDeps} {
+			for i := range list {
+				if list[i].Expected != nil && list[i].Actual != nil && list[i].Actual.Error == nil {
+					list[i].Log = c.commitLog(*list[i].Expected, *list[i].Actual)
+				}
+			}
+		}
 	}
+	return r
+}
 
-	for _, comparePkg := range result {
-		richPrefix[comparePkg.CompareResult]()
-		c.format.PrintLine(" %s %s", (comparePkg.ImportPath + padding)[0:maxLen], comparePkg.Message)
+//Compare is Report, rendered as a table unless the Quiet flag is set.
+func (c *Context) Compare(workingDir, pkgString string, tagSets []string, depsFile DepsFile, dotests bool) ([]ComparePkg, bool) {
+	report := c.Report(workingDir, pkgString, tagSets, depsFile, dotests)
+	if !c.flags.Checked(Quiet) {
+		NewTableRenderer(c.format).Render(report)
 	}
-	return result, ok
+	return report.All(), report.Ok()
+}
+
+//StatusError is recorded against a dependency whose checkout is not clean.
+type StatusError struct {
+	Kind       string //Import or Module
+	ImportPath string
+	Dir        string
+	Status     git.Status
+}
+
+func (e StatusError) Error() string {
+	return fmt.Sprintf("%s %s (%s) has git status %s", e.Kind, e.ImportPath, e.Dir, e.Status.String())
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/desal/dsutil"
 	"github.com/desal/git"
@@ -117,6 +118,11 @@ func TestCompare(t *testing.T) {
 	result, ok := compareCtx.Compare(m.gopath, "mainpkg", []string{""}, depsFile, true)
 	assert.False(t, ok)
 
+	assert.Equal(t, sha1before, result[0].Expected.SHA)
+	assert.Equal(t, sha1after, result[0].Actual.SHA)
+	assert.Equal(t, []string{"v2.0"}, result[0].Actual.Tags)
+	assert.False(t, result[0].TestDep)
+
 	assert.NotEqual(t, "", result[1].Message)
 	assert.Contains(t, result[1].Message, "Import depthree")
 	assert.Contains(t, result[1].Message, "has git status Uncommitted")
@@ -128,3 +134,106 @@ func TestCompare(t *testing.T) {
 }
 
 //TODO more output tests like this
+
+type recordingRenderer struct {
+	reports []snapshot.CompareReport
+}
+
+func (r *recordingRenderer) Render(report snapshot.CompareReport) error {
+	r.reports = append(r.reports, report)
+	return nil
+}
+
+func TestCompareDepsFiles(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2017, 1, d, 0, 0, 0, 0, time.UTC) }
+
+	expected := snapshot.DepsFile{
+		Deps: []snapshot.PkgDep{
+			{ImportPath: "dirty", SHA: "aaa"},
+			{ImportPath: "failed", SHA: "bbb"},
+			{ImportPath: "match", SHA: "ccc", Tags: []string{"v1"}},
+			{ImportPath: "moved", SHA: "ddd"},
+			{ImportPath: "newer", SHA: "eee", CommitTime: day(1)},
+			{ImportPath: "older", SHA: "fff", CommitTime: day(2)},
+			{ImportPath: "removed", SHA: "ggg"},
+			{ImportPath: "retagged", SHA: "hhh", Tags: []string{"v1"}},
+		},
+		TestDeps: []snapshot.PkgDep{
+			{ImportPath: "testdep", SHA: "iii"},
+		},
+	}
+	actual := snapshot.DepsFile{
+		Deps: []snapshot.PkgDep{
+			{ImportPath: "dirty", SHA: "aaa", Error: snapshot.StatusError{"Import", "dirty", "/src/dirty", git.Uncommitted}},
+			{ImportPath: "failed", Error: fmt.Errorf("Failed to scan dependency failed.")},
+			{ImportPath: "match", SHA: "ccc", Tags: []string{"v1"}},
+			{ImportPath: "moved", SHA: "111"},
+			{ImportPath: "new", SHA: "222"},
+			{ImportPath: "newer", SHA: "333", CommitTime: day(2)},
+			{ImportPath: "older", SHA: "444", CommitTime: day(1)},
+			{ImportPath: "retagged", SHA: "hhh", Tags: []string{"v1", "v2"}},
+		},
+		TestDeps: []snapshot.PkgDep{
+			{ImportPath: "testdep", SHA: "555"},
+		},
+	}
+
+	report := snapshot.CompareDepsFiles(expected, actual, true)
+
+	categories := map[string]snapshot.CompareCategory{}
+	severities := map[string]snapshot.CompareResult{}
+	for _, comparePkg := range report.Deps {
+		categories[comparePkg.ImportPath] = comparePkg.Category
+		severities[comparePkg.ImportPath] = comparePkg.CompareResult
+		assert.False(t, comparePkg.TestDep)
+	}
+	assert.Equal(t, map[string]snapshot.CompareCategory{
+		"dirty":    snapshot.CompareCategory_Dirty,
+		"failed":   snapshot.CompareCategory_Failed,
+		"match":    snapshot.CompareCategory_Match,
+		"moved":    snapshot.CompareCategory_Moved,
+		"new":      snapshot.CompareCategory_New,
+		"newer":    snapshot.CompareCategory_Newer,
+		"older":    snapshot.CompareCategory_Older,
+		"removed":  snapshot.CompareCategory_Removed,
+		"retagged": snapshot.CompareCategory_Retagged,
+	}, categories)
+	assert.Equal(t, map[string]snapshot.CompareResult{
+		"dirty":    snapshot.CompareResult_Error,
+		"failed":   snapshot.CompareResult_Error,
+		"match":    snapshot.CompareResult_Ok,
+		"moved":    snapshot.CompareResult_Error,
+		"new":      snapshot.CompareResult_Error,
+		"newer":    snapshot.CompareResult_Error,
+		"older":    snapshot.CompareResult_Error,
+		"removed":  snapshot.CompareResult_Warn,
+		"retagged": snapshot.CompareResult_Ok,
+	}, severities)
+
+	assert.Equal(t, "Import dirty (/src/dirty) has git status Uncommitted", report.Deps[0].Message)
+	assert.Equal(t, "(expected) [v1] vs (actual) [v1 v2]", report.Deps[8].Message)
+	assert.Equal(t, expected.Deps[3], *report.Deps[3].Expected)
+	assert.Equal(t, actual.Deps[3], *report.Deps[3].Actual)
+	assert.Nil(t, report.Deps[4].Expected)
+	assert.Nil(t, report.Deps[7].Actual)
+
+	require.Equal(t, 1, len(report.TestDeps))
+	assert.True(t, report.TestDeps[0].TestDep)
+	assert.Equal(t, snapshot.CompareCategory_Moved, report.TestDeps[0].Category)
+	assert.False(t, report.Ok())
+	assert.Equal(t, 10, len(report.All()))
+
+	report = snapshot.CompareDepsFiles(snapshot.DepsFile{Deps: expected.Deps[2:3], TestDeps: expected.TestDeps}, snapshot.DepsFile{Deps: actual.Deps[2:3]}, false)
+	assert.True(t, report.Ok())
+	assert.Equal(t, []snapshot.ComparePkg{}, report.TestDeps)
+
+	//Any renderer can present a report
+	renderer := &recordingRenderer{}
+	var _ snapshot.Renderer = renderer
+	require.Nil(t, renderer.Render(report))
+	assert.Equal(t, []snapshot.CompareReport{report}, renderer.reports)
+
+	buf := &bytes.Buffer{}
+	require.Nil(t, snapshot.NewTableRenderer(richtext.Debug(buf)).Render(report))
+	assert.Equal(t, "[Green,None,[Bold]][ OK ][] match \n", buf.String())
+}
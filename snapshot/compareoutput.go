@@ -0,0 +1,161 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+)
+
+//CompareOutput is a machine-readable format Compare results can be written
+//in, each dependency a test case.
+type CompareOutput string
+
+const (
+	CompareOutput_Json  CompareOutput = "json"
+	CompareOutput_JUnit CompareOutput = "junit"
+	CompareOutput_Tap   CompareOutput = "tap"
+)
+
+type (
+	junitTestSuite struct {
+		XMLName   xml.Name        `xml:"testsuite"`
+		Name      string          `xml:"name,attr"`
+		Tests     int             `xml:"tests,attr"`
+		Failures  int             `xml:"failures,attr"`
+		TestCases []junitTestCase `xml:"testcase"`
+	}
+
+	junitTestCase struct {
+		ClassName string        `xml:"classname,attr"`
+		Name      string        `xml:"name,attr"`
+		Failure   *junitFailure `xml:"failure,omitempty"`
+		SystemOut string        `xml:"system-out,omitempty"`
+	}
+
+	junitFailure struct {
+		Message string `xml:"message,attr"`
+		Details string `xml:",chardata"`
+	}
+)
+
+//WriteCompare writes the result of Compare or Verify to filename in the given
+//format.
+func WriteCompare(filename string, result []ComparePkg, output CompareOutput) error {
+	switch output {
+	case CompareOutput_Json:
+		return WriteCompareJson(filename, result)
+	case CompareOutput_JUnit:
+		return WriteCompareJUnit(filename, result)
+	case CompareOutput_Tap:
+		return WriteCompareTap(filename, result)
+	}
+	return fmt.Errorf("Unknown output format %s.", output)
+}
+
+func WriteCompareJson(filename string, result []ComparePkg) error {
+	jsonOutput, err := json.MarshalIndent(&result, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeOutput(filename, jsonOutput)
+}
+
+//compareDetails are the lines describing a ComparePkg beyond its message:
+//the expected and actual revisions and the commit log between them.
+func compareDetails(comparePkg ComparePkg) []string {
+	r := []string{}
+	describe := func(name string, pkgDep *PkgDep) {
+		if pkgDep == nil {
+			return
+		}
+		line := fmt.Sprintf("%s: %s", name, pkgDep.SHA)
+		if version := pkgDep.moduleVersion(); version != "" {
+			line += " " + version
+		}
+		if len(pkgDep.Tags) != 0 {
+			line += fmt.Sprintf(" %v", pkgDep.Tags)
+		}
+		if !pkgDep.CommitTime.IsZero() {
+			line += " " + pkgDep.CommitTime.Format(time.RFC3339)
+		}
+		r = append(r, line)
+	}
+	describe("expected", comparePkg.Expected)
+	describe("actual", comparePkg.Actual)
+
+	if log := comparePkg.Log; log != nil && log.Error != "" {
+		r = append(r, "log: "+log.Error)
+	} else if log != nil {
+		r = append(r, "log: "+logDirectionLabels[log.Direction])
+		for _, commit := range log.Added {
+			r = append(r, "  + "+shortSHA(commit.SHA)+" "+commit.Subject)
+		}
+		for _, commit := range log.Removed {
+			r = append(r, "  - "+shortSHA(commit.SHA)+" "+commit.Subject)
+		}
+	}
+	return r
+}
+
+func compareClass(comparePkg ComparePkg) string {
+	if comparePkg.TestDep {
+		return "TestDeps"
+	}
+	return "Deps"
+}
+
+//WriteCompareJUnit writes result as a JUnit XML test suite. Warnings pass,
+//with their message as output.
+func WriteCompareJUnit(filename string, result []ComparePkg) error {
+	suite := junitTestSuite{Name: "go-snap", Tests: len(result)}
+	for _, comparePkg := range result {
+		testCase := junitTestCase{ClassName: compareClass(comparePkg), Name: comparePkg.ImportPath}
+		details := strings.Join(compareDetails(comparePkg), "\n")
+		switch comparePkg.CompareResult {
+		case CompareResult_Error:
+			suite.Failures++
+			testCase.Failure = &junitFailure{Message: comparePkg.Message, Details: details}
+		case CompareResult_Warn:
+			testCase.SystemOut = strings.TrimSpace("WARN " + comparePkg.Message + "\n" + details)
+		}
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+
+	xmlOutput, err := xml.MarshalIndent(&suite, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeOutput(filename, append([]byte(xml.Header), append(xmlOutput, '\n')...))
+}
+
+//WriteCompareTap writes result in the Test Anything Protocol, version 13,
+//with the details of any warning or failure as a YAML block.
+func WriteCompareTap(filename string, result []ComparePkg) error {
+	lines := []string{"TAP version 13", fmt.Sprintf("1..%d", len(result))}
+	for i, comparePkg := range result {
+		status := "ok"
+		if comparePkg.CompareResult == CompareResult_Error {
+			status = "not ok"
+		}
+		lines = append(lines, fmt.Sprintf("%s %d - %s %s", status, i+1, compareClass(comparePkg), comparePkg.ImportPath))
+
+		if comparePkg.CompareResult == CompareResult_Ok {
+			continue
+		}
+		lines = append(lines, "  ---",
+			fmt.Sprintf("  severity: %s", compareResultLabels[comparePkg.CompareResult]),
+			fmt.Sprintf("  message: %q", comparePkg.Message))
+		if details := compareDetails(comparePkg); len(details) != 0 {
+			lines = append(lines, "  details: |")
+			for _, line := range details {
+				lines = append(lines, "    "+line)
+			}
+		}
+		lines = append(lines, "  ...")
+	}
+
+	return writeOutput(filename, []byte(strings.Join(lines, "\n")+"\n"))
+}
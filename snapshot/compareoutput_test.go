@@ -0,0 +1,96 @@
+package snapshot_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/desal/go-snap/snapshot"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteCompare(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "go-snap-compare")
+	require.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	commitTime := time.Date(2017, 3, 4, 5, 6, 7, 0, time.UTC)
+	result := []snapshot.ComparePkg{
+		{
+			ImportPath:    "depone",
+			Message:       "(expected) aaaaaa vs (actual) bbbbbb",
+			CompareResult: snapshot.CompareResult_Error,
+			Expected:      &snapshot.PkgDep{ImportPath: "depone", SHA: "aaaaaaaa", Tags: []string{"v1.0"}, CommitTime: commitTime},
+			Actual:        &snapshot.PkgDep{ImportPath: "depone", SHA: "bbbbbbbb"},
+			Log: &snapshot.CommitLog{From: "aaaaaaaa", To: "bbbbbbbb", Direction: snapshot.LogDirection_FastForward,
+				Added: []snapshot.Commit{{"bbbbbbbb", "fix <things> & stuff"}}},
+		},
+		{
+			ImportPath:    "deptwo",
+			CompareResult: snapshot.CompareResult_Ok,
+			Expected:      &snapshot.PkgDep{ImportPath: "deptwo", SHA: "cccccccc"},
+			Actual:        &snapshot.PkgDep{ImportPath: "deptwo", SHA: "cccccccc"},
+		},
+		{
+			ImportPath:    "testdep",
+			Message:       "No longer requried",
+			CompareResult: snapshot.CompareResult_Warn,
+			TestDep:       true,
+			Expected:      &snapshot.PkgDep{ImportPath: "testdep", SHA: "dddddddd"},
+		},
+	}
+
+	read := func(output snapshot.CompareOutput) string {
+		filename := filepath.Join(tempDir, string(output))
+		require.Nil(t, snapshot.WriteCompare(filename, result, output))
+		content, err := ioutil.ReadFile(filename)
+		require.Nil(t, err)
+		return string(content)
+	}
+
+	jsonOutput := read(snapshot.CompareOutput_Json)
+	assert.Contains(t, jsonOutput, `"CompareResult": "error"`)
+	assert.Contains(t, jsonOutput, `"TestDep": true`)
+	assert.Contains(t, jsonOutput, `"SHA": "aaaaaaaa"`)
+	assert.Contains(t, jsonOutput, `"CommitTime": "2017-03-04T05:06:07Z"`)
+	assert.Contains(t, jsonOutput, `"Direction": "fast-forward"`)
+
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuite name="go-snap" tests="3" failures="1">
+  <testcase classname="Deps" name="depone">
+    <failure message="(expected) aaaaaa vs (actual) bbbbbb">expected: aaaaaaaa [v1.0] 2017-03-04T05:06:07Z&#xA;actual: bbbbbbbb&#xA;log: fast-forward&#xA;  + bbbbbb fix &lt;things&gt; &amp; stuff</failure>
+  </testcase>
+  <testcase classname="Deps" name="deptwo"></testcase>
+  <testcase classname="TestDeps" name="testdep">
+    <system-out>WARN No longer requried&#xA;expected: dddddddd</system-out>
+  </testcase>
+</testsuite>
+`, read(snapshot.CompareOutput_JUnit))
+
+	assert.Equal(t, `TAP version 13
+1..3
+not ok 1 - Deps depone
+  ---
+  severity: error
+  message: "(expected) aaaaaa vs (actual) bbbbbb"
+  details: |
+    expected: aaaaaaaa [v1.0] 2017-03-04T05:06:07Z
+    actual: bbbbbbbb
+    log: fast-forward
+      + bbbbbb fix <things> & stuff
+  ...
+ok 2 - Deps deptwo
+ok 3 - TestDeps testdep
+  ---
+  severity: warn
+  message: "No longer requried"
+  details: |
+    expected: dddddddd
+  ...
+`, read(snapshot.CompareOutput_Tap))
+
+	assert.EqualError(t, snapshot.WriteCompare(filepath.Join(tempDir, "csv"), result, "csv"), "Unknown output format csv.")
+}
@@ -0,0 +1,157 @@
+package snapshot
+
+import (
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+//ConfigFilename is the name of the project configuration file, looked for in
+//the working directory and each of its parents.
+const ConfigFilename = ".go-snap.yml"
+
+type (
+	//Config is the project configuration. It is an Option, so a Context can
+	//be built from it, and command line flags take precedence over it.
+	Config struct {
+		Packages   []string             `yaml:"packages,omitempty"`    //Snapshotted and compared by default
+		TagSets    []string             `yaml:"tags,omitempty"`        //Build tag sets, as with --tags
+		Platforms  Platforms            `yaml:"platforms,omitempty"`   //GOOS/GOARCH pairs, as with --platform
+		Filename   string               `yaml:"filename,omitempty"`    //Of the snapshot, snapshot.json by default
+		SkipTests  bool                 `yaml:"notests,omitempty"`     //Leave out test dependencies, as with -t
+		Ignore     []string             `yaml:"ignore,omitempty"`      //Import path globs Snapshot leaves out
+		AllowDirty []string             `yaml:"allow-dirty,omitempty"` //Import path globs recorded even if dirty
+		Deps       map[string]DepConfig `yaml:"deps,omitempty"`        //By root import path
+		Policy     Policy               `yaml:"policy,omitempty"`
+	}
+
+	//DepConfig overrides what is recorded for a single dependency.
+	//Constraint limits update to semver tags it allows, such as ^1.2.
+	DepConfig struct {
+		Branch     string `yaml:"branch,omitempty"`
+		Remote     string `yaml:"remote,omitempty"` //Cloned from instead of the recorded remote
+		Constraint string `yaml:"constraint,omitempty"`
+	}
+)
+
+func (cfg Config) apply(c *Context) {
+	c.config = cfg
+	c.policy = c.policy.Merge(cfg.Policy)
+	Ignore(cfg.Ignore).apply(c)
+	AllowDirty(cfg.AllowDirty).apply(c)
+	if len(cfg.Platforms) != 0 {
+		c.platforms = cfg.Platforms
+	}
+}
+
+//FindConfig returns the path of the nearest configuration file to dir, or
+//blank if there isn't one.
+func FindConfig(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		filename := filepath.Join(dir, ConfigFilename)
+		if _, err := os.Stat(filename); err == nil {
+			return filename, nil
+		} else if !os.IsNotExist(err) {
+			return "", err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+//ReadConfig reads a configuration file. A relative Filename, and packages
+//given as relative directories such as ./..., are made relative to the
+//directory of filename rather than the working directory.
+func ReadConfig(filename string) (Config, error) {
+	var cfg Config
+
+	input, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return cfg, err
+	}
+
+	if err = yaml.UnmarshalStrict(input, &cfg); err != nil {
+		return cfg, err
+	}
+
+	dir := filepath.Dir(filename)
+	if cfg.Filename != "" && !filepath.IsAbs(cfg.Filename) {
+		cfg.Filename = filepath.Join(dir, cfg.Filename)
+	}
+	for i, pkg := range cfg.Packages {
+		if build.IsLocalImport(pkg) {
+			cfg.Packages[i] = filepath.Join(dir, pkg)
+		}
+	}
+	return cfg, nil
+}
+
+//LoadConfig reads the nearest configuration file to dir, an empty Config if
+//there isn't one.
+func LoadConfig(dir string) (Config, error) {
+	filename, err := FindConfig(dir)
+	if err != nil || filename == "" {
+		return Config{}, err
+	}
+	return ReadConfig(filename)
+}
+
+//branch is the branch pkgDep should follow, the configured override if there
+//is one, otherwise the branch it was snapshotted on.
+func (c *Context) branch(pkgDep PkgDep) string {
+	if depConfig, ok := c.config.Deps[pkgDep.ImportPath]; ok && depConfig.Branch != "" {
+		return depConfig.Branch
+	}
+	return pkgDep.Branch
+}
+
+//remote is where pkgDep is cloned or fetched from, the configured override if
+//there is one, otherwise the remote it was snapshotted with.
+func (c *Context) remote(pkgDep PkgDep) string {
+	if depConfig, ok := c.config.Deps[pkgDep.ImportPath]; ok && depConfig.Remote != "" {
+		return depConfig.Remote
+	}
+	return pkgDep.GitRemote
+}
+
+//ignored is true if importPath matches any of the ignore globs.
+func (c *Context) ignored(importPath string) bool {
+	return matchesAny(c.ignore, importPath)
+}
+
+//withoutIgnored is depsFile without the dependencies matching the ignore
+//globs.
+func (c *Context) withoutIgnored(depsFile DepsFile) DepsFile {
+	filter := func(pkgDeps []PkgDep) []PkgDep {
+		r := []PkgDep{}
+		for _, pkgDep := range pkgDeps {
+			if !c.ignored(pkgDep.ImportPath) {
+				r = append(r, pkgDep)
+			}
+		}
+		return r
+	}
+	return DepsFile{Deps: filter(depsFile.Deps), TestDeps: filter(depsFile.TestDeps)}
+}
+
+//dirtyAllowed is true if any of importPaths match the allow dirty globs.
+func (c *Context) dirtyAllowed(importPaths ...string) bool {
+	for _, importPath := range importPaths {
+		if matchesAny(c.allowDirty, importPath) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+package snapshot_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/desal/dsutil"
+	"github.com/desal/go-snap/snapshot"
+	"github.com/desal/richtext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot_test_config")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	subDir := filepath.Join(dir, "sub", "dir")
+	require.Nil(t, os.MkdirAll(subDir, 0755))
+
+	config, err := snapshot.LoadConfig(subDir)
+	require.Nil(t, err)
+	assert.Equal(t, snapshot.Config{}, config)
+
+	err = ioutil.WriteFile(filepath.Join(dir, snapshot.ConfigFilename), []byte(`
+deps:
+  github.com/desal/git:
+    branch: develop
+`), 0644)
+	require.Nil(t, err)
+
+	filename, err := snapshot.FindConfig(subDir)
+	require.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, snapshot.ConfigFilename), filename)
+
+	config, err = snapshot.LoadConfig(subDir)
+	require.Nil(t, err)
+	assert.Equal(t, snapshot.Config{Deps: map[string]snapshot.DepConfig{"github.com/desal/git": {Branch: "develop"}}}, config)
+
+	err = ioutil.WriteFile(filepath.Join(subDir, snapshot.ConfigFilename), []byte("unknown: true\n"), 0644)
+	require.Nil(t, err)
+	_, err = snapshot.LoadConfig(subDir)
+	assert.NotNil(t, err)
+}
+
+func TestReadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot_test_config")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, snapshot.ConfigFilename)
+	err = ioutil.WriteFile(filename, []byte(`
+packages: [github.com/desal/go-snap/..., ./cmd/...]
+tags: ["", "integration"]
+platforms: [linux/amd64, windows/amd64]
+filename: deps.json
+notests: true
+ignore:
+  - github.com/desal/internal/...
+allow-dirty: [github.com/desal/generated]
+deps:
+  github.com/desal/git:
+    remote: https://mirror.example.com/git
+    constraint: ^1.2
+policy:
+  newer: ok
+  test-only: warn
+`), 0644)
+	require.Nil(t, err)
+
+	config, err := snapshot.ReadConfig(filename)
+	require.Nil(t, err)
+	assert.Equal(t, snapshot.Config{
+		Packages:   []string{"github.com/desal/go-snap/...", filepath.Join(dir, "cmd", "...")},
+		TagSets:    []string{"", "integration"},
+		Platforms:  snapshot.Platforms{"linux/amd64", "windows/amd64"},
+		Filename:   filepath.Join(dir, "deps.json"),
+		SkipTests:  true,
+		Ignore:     []string{"github.com/desal/internal/..."},
+		AllowDirty: []string{"github.com/desal/generated"},
+		Deps: map[string]snapshot.DepConfig{
+			"github.com/desal/git": {Remote: "https://mirror.example.com/git", Constraint: "^1.2"},
+		},
+		Policy: snapshot.Policy{"newer": snapshot.CompareResult_Ok, "test-only": snapshot.CompareResult_Warn},
+	}, config)
+
+	err = ioutil.WriteFile(filename, []byte("policy:\n  newest: ok\n"), 0644)
+	require.Nil(t, err)
+	_, err = snapshot.ReadConfig(filename)
+	assert.NotNil(t, err)
+}
+
+func TestConfigIgnoreAndRemote(t *testing.T) {
+	m := SetupRepos(t)
+	defer m.Close()
+
+	m.AddRepo("depone")
+	m.AddRepo("deptwo")
+	m.AddRepo("mainpkg")
+
+	m.goCtx.Execf(`
+		cd src/depone;
+		echo 'package depone\n\nconst One = 12' > depone.go;
+		git add -A;
+		git commit -m "gocode";
+		git push`)
+	m.goCtx.Execf(`
+		cd src/deptwo;
+		echo 'package deptwo\n\nconst Two = 3' > deptwo.go;
+		git add -A;
+		git commit -m "gocode";
+		git push`)
+	m.goCtx.Execf(`
+		cd src/mainpkg;
+		echo 'package main\n\nimport (\n\t"depone"\n\t"deptwo"\n)\n\nvar _ = depone.One * deptwo.Two' > main.go;
+		git add -A;
+		git commit -m "gocode";
+		git push`)
+
+	//deptwo can be dirty, it's not recorded at all
+	m.goCtx.Execf(`echo 'extra' > src/deptwo/extra.txt`)
+	config := snapshot.Config{Ignore: []string{"dept..."}}
+	ctx := snapshot.New(richtext.Test(t), []string{m.gopath}, config)
+	depsFile, err := ctx.Snapshot(m.gopath, "mainpkg", []string{""})
+	require.Nil(t, err)
+	require.Equal(t, 1, len(depsFile.Deps))
+	assert.Equal(t, "depone", depsFile.Deps[0].ImportPath)
+
+	//Given as well, flags replace what is configured rather than adding to it
+	options := []snapshot.Option{config, snapshot.Ignore{"depone"}, snapshot.AllowDirty{"deptwo"}}
+	ctx = snapshot.New(richtext.Test(t), []string{m.gopath}, options...)
+	replaced, err := ctx.Snapshot(m.gopath, "mainpkg", []string{""})
+	require.Nil(t, err)
+	require.Equal(t, 1, len(replaced.Deps))
+	assert.Equal(t, "deptwo", replaced.Deps[0].ImportPath)
+
+	//Cloned from the configured remote rather than the recorded one
+	require.Nil(t, os.RemoveAll(filepath.Join(m.gopath, "src", "depone")))
+	depsFile.Deps[0].GitRemote = filepath.Join(m.bareDir, "missing")
+	config = snapshot.Config{Deps: map[string]snapshot.DepConfig{"depone": {Remote: filepath.Join(m.bareDir, "depone")}}}
+	ctx = snapshot.New(richtext.Test(t), []string{m.gopath}, config)
+	result, err := ctx.Reproduce(m.gopath, depsFile, true, snapshot.AlreadyExists_Fail)
+	require.Nil(t, err)
+	assert.Equal(t, snapshot.Action_Clone, result[0].Action)
+	assert.True(t, dsutil.CheckPath(filepath.Join(m.gopath, "src", "depone", "depone.go")))
+}
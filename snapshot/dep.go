@@ -0,0 +1,75 @@
+package snapshot
+
+import (
+	"bytes"
+
+	"github.com/BurntSushi/toml"
+)
+
+type depLock struct {
+	Projects []depProject `toml:"projects"`
+}
+
+type depProject struct {
+	Name     string   `toml:"name"`
+	Branch   string   `toml:"branch,omitempty"`
+	Revision string   `toml:"revision"`
+	Version  string   `toml:"version,omitempty"`
+	Source   string   `toml:"source,omitempty"`
+	Packages []string `toml:"packages"`
+}
+
+//ImportDep reads a Gopkg.lock file from dep.
+func ImportDep(filename string) (DepsFile, error) {
+	var lock depLock
+
+	r, err := openInput(filename)
+	if err != nil {
+		return DepsFile{}, err
+	}
+	defer r.Close()
+
+	if _, err := toml.DecodeReader(r, &lock); err != nil {
+		return DepsFile{}, err
+	}
+
+	deps := []PkgDep{}
+	for _, project := range lock.Projects {
+		pkgDep := PkgDep{ImportPath: project.Name, GitRemote: project.Source, SHA: project.Revision, Branch: project.Branch}
+		if pkgDep.GitRemote == "" {
+			pkgDep.GitRemote = repoRemote(project.Name)
+		}
+		if project.Version != "" {
+			pkgDep.Tags = []string{project.Version}
+		}
+		deps = append(deps, pkgDep)
+	}
+
+	depsFile := DepsFile{Deps: deps, TestDeps: []PkgDep{}}
+	depsFile.Sort()
+	return depsFile, nil
+}
+
+//WriteDep writes depsFile as a Gopkg.lock file. Which packages of each
+//project are used isn't recorded, so every project lists only its root.
+func WriteDep(filename string, depsFile DepsFile) error {
+	lock := depLock{Projects: []depProject{}}
+	for _, pkgDep := range allDeps(depsFile) {
+		project := depProject{Name: pkgDep.ImportPath, Revision: pkgDep.SHA, Version: firstTag(pkgDep), Packages: []string{"."}}
+		if pkgDep.GitRemote != repoRemote(pkgDep.ImportPath) {
+			project.Source = pkgDep.GitRemote
+		}
+		if project.Version == "" {
+			project.Branch = pkgDep.Branch
+		}
+		lock.Projects = append(lock.Projects, project)
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("# This file is autogenerated by go-snap, do not edit.\n\n")
+	if err := toml.NewEncoder(buf).Encode(&lock); err != nil {
+		return err
+	}
+
+	return writeOutput(filename, buf.Bytes())
+}
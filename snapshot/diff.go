@@ -0,0 +1,185 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/desal/richtext"
+)
+
+//go:generate stringer -type DiffKind
+
+type DiffKind int
+
+const (
+	DiffKind_Added DiffKind = iota
+	DiffKind_Removed
+	DiffKind_Upgraded
+	DiffKind_Downgraded
+	DiffKind_Changed //Moved with no commit time or version to tell which way
+	DiffKind_Retagged
+)
+
+//DiffPkg is a dependency that differs between two snapshots. Old is nil when
+//it was added, New when it was removed. Log is only set by AddLogs.
+type DiffPkg struct {
+	ImportPath string
+	Kind       DiffKind
+	Old        *PkgDep    `json:",omitempty"`
+	New        *PkgDep    `json:",omitempty"`
+	Log        *CommitLog `json:",omitempty"`
+}
+
+//DepsFileDiff is every dependency that differs between two snapshots, each
+//list sorted by import path.
+type DepsFileDiff struct {
+	Deps     []DiffPkg
+	TestDeps []DiffPkg
+}
+
+type DiffPkgs []DiffPkg
+
+func (a DiffPkgs) Len() int           { return len(a) }
+func (a DiffPkgs) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a DiffPkgs) Less(i, j int) bool { return a[i].ImportPath < a[j].ImportPath }
+
+func (a DiffKind) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strings.ToLower(strings.TrimPrefix(a.String(), "DiffKind_")))
+}
+
+//direction is whether newDep is an upgrade or downgrade of oldDep, by commit
+//time, or by module version when either has no commit time.
+func direction(oldDep, newDep PkgDep) DiffKind {
+	if !oldDep.CommitTime.IsZero() && !newDep.CommitTime.IsZero() {
+		if newDep.CommitTime.After(oldDep.CommitTime) {
+			return DiffKind_Upgraded
+		} else if newDep.CommitTime.Before(oldDep.CommitTime) {
+			return DiffKind_Downgraded
+		}
+		return DiffKind_Changed
+	}
+
+	oldVersion, oldOk := parseSemver(oldDep.moduleVersion())
+	newVersion, newOk := parseSemver(newDep.moduleVersion())
+	if oldOk && newOk {
+		switch newVersion.compare(oldVersion) {
+		case 1:
+			return DiffKind_Upgraded
+		case -1:
+			return DiffKind_Downgraded
+		}
+	}
+	return DiffKind_Changed
+}
+
+func diffPkgDeps(oldDeps, newDeps []PkgDep) []DiffPkg {
+	r := []DiffPkg{}
+	oldMap := map[string]PkgDep{}
+	for _, oldDep := range oldDeps {
+		oldMap[oldDep.ImportPath] = oldDep
+	}
+
+	for _, pkgDep := range newDeps {
+		newDep := pkgDep
+		oldDep, hasOld := oldMap[newDep.ImportPath]
+		if !hasOld {
+			r = append(r, DiffPkg{ImportPath: newDep.ImportPath, Kind: DiffKind_Added, New: &newDep})
+			continue
+		}
+		delete(oldMap, newDep.ImportPath)
+
+		if oldDep.SHA != newDep.SHA || oldDep.moduleVersion() != newDep.moduleVersion() {
+			r = append(r, DiffPkg{ImportPath: newDep.ImportPath, Kind: direction(oldDep, newDep), Old: &oldDep, New: &newDep})
+		} else if !reflect.DeepEqual(oldDep.Tags, newDep.Tags) && (len(oldDep.Tags) != 0 || len(newDep.Tags) != 0) {
+			r = append(r, DiffPkg{ImportPath: newDep.ImportPath, Kind: DiffKind_Retagged, Old: &oldDep, New: &newDep})
+		}
+	}
+
+	for importPath := range oldMap {
+		oldDep := oldMap[importPath]
+		r = append(r, DiffPkg{ImportPath: importPath, Kind: DiffKind_Removed, Old: &oldDep})
+	}
+
+	sort.Sort(DiffPkgs(r))
+	return r
+}
+
+//DiffDepsFiles reports the dependencies added, removed, moved or retagged
+//between oldFile and newFile, without looking at what's on disk.
+func DiffDepsFiles(oldFile, newFile DepsFile) DepsFileDiff {
+	return DepsFileDiff{
+		Deps:     diffPkgDeps(oldFile.Deps, newFile.Deps),
+		TestDeps: diffPkgDeps(oldFile.TestDeps, newFile.TestDeps),
+	}
+}
+
+//label is the revision of pkgDep and its tags, if any.
+func (p PkgDep) label() string {
+	if len(p.Tags) != 0 {
+		return fmt.Sprintf("%s %v", p.revision(), p.Tags)
+	}
+	return p.revision()
+}
+
+func (c *Context) PrintDiff(diff DepsFileDiff) {
+	maxLen := 0
+	for _, diffPkg := range append(append([]DiffPkg{}, diff.Deps...), diff.TestDeps...) {
+		if len(diffPkg.ImportPath) > maxLen {
+			maxLen = len(diffPkg.ImportPath)
+		}
+	}
+
+	padding := strings.Repeat(" ", maxLen)
+
+	green := c.format.MakePrintf(richtext.Green, richtext.None, richtext.Bold)
+	orange := c.format.MakePrintf(richtext.Orange, richtext.None, richtext.Bold)
+	red := c.format.MakePrintf(richtext.Red, richtext.None, richtext.Bold)
+
+	richPrefix := map[DiffKind]func(){
+		DiffKind_Added:      func() { green("[ ADD]") },
+		DiffKind_Removed:    func() { orange("[ DEL]") },
+		DiffKind_Upgraded:   func() { green("[ UP ]") },
+		DiffKind_Downgraded: func() { red("[DOWN]") },
+		DiffKind_Changed:    func() { orange("[ CHG]") },
+		DiffKind_Retagged:   func() { orange("[ TAG]") },
+	}
+
+	printList := func(title string, diffPkgs []DiffPkg) {
+		if len(diffPkgs) == 0 {
+			return
+		}
+		c.format.PrintLine("%s:", title)
+		for _, diffPkg := range diffPkgs {
+			var message string
+			switch diffPkg.Kind {
+			case DiffKind_Added:
+				message = diffPkg.New.label()
+			case DiffKind_Removed:
+				message = diffPkg.Old.label()
+			default:
+				message = diffPkg.Old.label() + " -> " + diffPkg.New.label()
+			}
+			richPrefix[diffPkg.Kind]()
+			c.format.PrintLine(" %s %s", (diffPkg.ImportPath + padding)[0:maxLen], message)
+			printCommitLog(c.format, strings.Repeat(" ", 8+maxLen), diffPkg.Log)
+		}
+	}
+
+	printList("Deps", diff.Deps)
+	printList("TestDeps", diff.TestDeps)
+	if len(diff.Deps) == 0 && len(diff.TestDeps) == 0 {
+		c.format.PrintLine("No differences")
+	}
+}
+
+func WriteDiffJson(filename string, diff DepsFileDiff) error {
+	jsonOutput, err := json.MarshalIndent(&diff, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeOutput(filename, jsonOutput)
+}
@@ -0,0 +1,82 @@
+package snapshot_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/desal/go-snap/snapshot"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffDepsFiles(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2017, 1, d, 0, 0, 0, 0, time.UTC) }
+
+	oldFile := snapshot.DepsFile{
+		Deps: []snapshot.PkgDep{
+			{ImportPath: "removed", SHA: "aaa", CommitTime: day(1)},
+			{ImportPath: "same", SHA: "bbb", CommitTime: day(1), Tags: []string{"v1"}},
+			{ImportPath: "upgraded", SHA: "ccc", CommitTime: day(1)},
+			{ImportPath: "downgraded", SHA: "ddd", CommitTime: day(2)},
+			{ImportPath: "retagged", SHA: "eee", CommitTime: day(1)},
+			{ImportPath: "untimed", SHA: "fff"},
+			{ImportPath: "module", Module: &snapshot.Module{Path: "module", Version: "v1.2.0"}},
+		},
+		TestDeps: []snapshot.PkgDep{
+			{ImportPath: "testdep", SHA: "ggg", CommitTime: day(1)},
+		},
+	}
+	newFile := snapshot.DepsFile{
+		Deps: []snapshot.PkgDep{
+			{ImportPath: "added", SHA: "111", CommitTime: day(1)},
+			{ImportPath: "same", SHA: "bbb", CommitTime: day(1), Tags: []string{"v1"}},
+			{ImportPath: "upgraded", SHA: "333", CommitTime: day(2)},
+			{ImportPath: "downgraded", SHA: "444", CommitTime: day(1)},
+			{ImportPath: "retagged", SHA: "eee", CommitTime: day(1), Tags: []string{"v2"}},
+			{ImportPath: "untimed", SHA: "666"},
+			{ImportPath: "module", Module: &snapshot.Module{Path: "module", Version: "v1.10.0"}},
+		},
+		TestDeps: []snapshot.PkgDep{
+			{ImportPath: "testdep", SHA: "777", CommitTime: day(2)},
+		},
+	}
+
+	diff := snapshot.DiffDepsFiles(oldFile, newFile)
+
+	kinds := map[string]snapshot.DiffKind{}
+	for _, diffPkg := range diff.Deps {
+		kinds[diffPkg.ImportPath] = diffPkg.Kind
+	}
+	assert.Equal(t, map[string]snapshot.DiffKind{
+		"added":      snapshot.DiffKind_Added,
+		"removed":    snapshot.DiffKind_Removed,
+		"upgraded":   snapshot.DiffKind_Upgraded,
+		"downgraded": snapshot.DiffKind_Downgraded,
+		"retagged":   snapshot.DiffKind_Retagged,
+		"untimed":    snapshot.DiffKind_Changed,
+		"module":     snapshot.DiffKind_Upgraded,
+	}, kinds)
+
+	assert.Equal(t, "added", diff.Deps[0].ImportPath)
+	assert.Nil(t, diff.Deps[0].Old)
+	assert.Equal(t, newFile.Deps[0], *diff.Deps[0].New)
+	assert.Equal(t, "upgraded", diff.Deps[len(diff.Deps)-1].ImportPath)
+	assert.Equal(t, oldFile.Deps[2], *diff.Deps[len(diff.Deps)-1].Old)
+	assert.Equal(t, newFile.Deps[2], *diff.Deps[len(diff.Deps)-1].New)
+
+	require.Equal(t, 1, len(diff.TestDeps))
+	assert.Equal(t, snapshot.DiffKind_Upgraded, diff.TestDeps[0].Kind)
+
+	//Swapped around everything moves the other way
+	diff = snapshot.DiffDepsFiles(newFile, oldFile)
+	assert.Equal(t, snapshot.DiffKind_Removed, diff.Deps[0].Kind)
+	assert.Equal(t, snapshot.DiffKind_Downgraded, diff.TestDeps[0].Kind)
+
+	diff = snapshot.DiffDepsFiles(oldFile, oldFile)
+	assert.Equal(t, snapshot.DepsFileDiff{Deps: []snapshot.DiffPkg{}, TestDeps: []snapshot.DiffPkg{}}, diff)
+
+	kind, err := json.Marshal(snapshot.DiffKind_Downgraded)
+	require.Nil(t, err)
+	assert.Equal(t, `"downgraded"`, string(kind))
+}
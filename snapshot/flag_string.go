@@ -4,9 +4,9 @@ package snapshot
 
 import "fmt"
 
-const _Flag_name = "MustExitMustPanicWarnVerboseCmdVerboseSkipVendor"
+const _Flag_name = "MustExitMustPanicWarnVerboseCmdVerboseSkipVendorAtomicVendorStripTestsStripAssetsOfflineLogQuietImportGraph"
 
-var _Flag_index = [...]uint8{0, 8, 17, 21, 28, 38, 48}
+var _Flag_index = [...]uint8{0, 8, 17, 21, 28, 38, 48, 54, 60, 70, 81, 88, 91, 96, 107}
 
 func (i Flag) String() string {
 	i -= 1
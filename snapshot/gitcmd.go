@@ -0,0 +1,16 @@
+package snapshot
+
+//gitExecf runs a git command in dir for the operations the git package
+//doesn't provide, returning its trimmed output.
+func (c *Context) gitExecf(dir, format string, a ...interface{}) (string, error) {
+	return c.vcsExecf(dir, "git", format, a...)
+}
+
+//gitBranch returns the branch checked out in dir, or blank when detached.
+func (c *Context) gitBranch(dir string) (string, error) {
+	branch, err := c.gitExecf(dir, "rev-parse --abbrev-ref HEAD")
+	if branch == "HEAD" {
+		branch = ""
+	}
+	return branch, err
+}
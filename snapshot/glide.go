@@ -0,0 +1,107 @@
+package snapshot
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+type glideLock struct {
+	Hash        string     `yaml:"hash"`
+	Updated     time.Time  `yaml:"updated"`
+	Imports     []glideDep `yaml:"imports"`
+	TestImports []glideDep `yaml:"testImports"`
+}
+
+type glideDep struct {
+	Name        string   `yaml:"name"`
+	Version     string   `yaml:"version"`
+	Repo        string   `yaml:"repo,omitempty"`
+	VCS         string   `yaml:"vcs,omitempty"`
+	Subpackages []string `yaml:"subpackages,omitempty"`
+}
+
+//ImportGlide reads a glide.lock file, keeping its test imports as TestDeps.
+func ImportGlide(filename string) (DepsFile, error) {
+	var lock glideLock
+
+	r, err := openInput(filename)
+	if err != nil {
+		return DepsFile{}, err
+	}
+	defer r.Close()
+
+	input, err := ioutil.ReadAll(r)
+	if err != nil {
+		return DepsFile{}, err
+	}
+
+	if err := yaml.Unmarshal(input, &lock); err != nil {
+		return DepsFile{}, err
+	}
+
+	convert := func(deps []glideDep) []PkgDep {
+		r := []PkgDep{}
+		for _, dep := range deps {
+			pkgDep := PkgDep{ImportPath: dep.Name, GitRemote: dep.Repo, SHA: dep.Version}
+			if dep.VCS != "git" {
+				pkgDep.VCS = dep.VCS
+			}
+			if pkgDep.GitRemote == "" {
+				pkgDep.GitRemote = repoRemote(dep.Name)
+			}
+			r = append(r, pkgDep)
+		}
+		return r
+	}
+
+	depsFile := DepsFile{Deps: convert(lock.Imports), TestDeps: convert(lock.TestImports)}
+	depsFile.Sort()
+	return depsFile, nil
+}
+
+//WriteGlide writes depsFile as a glide.lock file. As there is no glide.yaml
+//the hash is of depsFile itself, and updated is the newest commit time, so
+//the same snapshot always gives the same lock file.
+func WriteGlide(filename string, depsFile DepsFile) error {
+	jsonOutput, err := json.Marshal(&depsFile)
+	if err != nil {
+		return err
+	}
+
+	lock := glideLock{Hash: fmt.Sprintf("%x", sha256.Sum256(jsonOutput))}
+
+	convert := func(pkgDeps []PkgDep) []glideDep {
+		r := []glideDep{}
+		for _, pkgDep := range pkgDeps {
+			dep := glideDep{Name: pkgDep.ImportPath, Version: pkgDep.SHA}
+			if pkgDep.VCS != "" {
+				dep.Repo = pkgDep.GitRemote
+				dep.VCS = pkgDep.VCS
+			} else if pkgDep.GitRemote != repoRemote(pkgDep.ImportPath) {
+				dep.Repo = pkgDep.GitRemote
+				dep.VCS = "git"
+			}
+			if pkgDep.CommitTime.After(lock.Updated) {
+				lock.Updated = pkgDep.CommitTime.UTC()
+			}
+			r = append(r, dep)
+		}
+		return r
+	}
+
+	depsFile.Sort()
+	lock.Imports = convert(depsFile.Deps)
+	lock.TestImports = convert(depsFile.TestDeps)
+
+	yamlOutput, err := yaml.Marshal(&lock)
+	if err != nil {
+		return err
+	}
+
+	return writeOutput(filename, yamlOutput)
+}
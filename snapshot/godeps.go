@@ -0,0 +1,60 @@
+package snapshot
+
+import (
+	"encoding/json"
+)
+
+type godepsFile struct {
+	ImportPath string `json:",omitempty"`
+	GoVersion  string `json:",omitempty"`
+	Deps       []godepsDep
+}
+
+type godepsDep struct {
+	ImportPath string
+	Comment    string `json:",omitempty"`
+	Rev        string
+}
+
+//ImportGodeps reads a Godeps/Godeps.json file from godep.
+func ImportGodeps(filename string) (DepsFile, error) {
+	var godeps godepsFile
+
+	r, err := openInput(filename)
+	if err != nil {
+		return DepsFile{}, err
+	}
+	defer r.Close()
+
+	if err := json.NewDecoder(r).Decode(&godeps); err != nil {
+		return DepsFile{}, err
+	}
+
+	pkgDeps := []PkgDep{}
+	for _, dep := range godeps.Deps {
+		pkgDep := PkgDep{ImportPath: dep.ImportPath, SHA: dep.Rev}
+		if isTag(dep.Comment) {
+			pkgDep.Tags = []string{dep.Comment}
+		}
+		pkgDeps = append(pkgDeps, pkgDep)
+	}
+
+	deps, err := groupPackages(pkgDeps)
+	return DepsFile{Deps: deps, TestDeps: []PkgDep{}}, err
+}
+
+//WriteGodeps writes depsFile as a Godeps.json file. Godeps has no separate
+//test dependencies, and always fetches with go get, so GitRemote is lost.
+func WriteGodeps(filename string, depsFile DepsFile) error {
+	godeps := godepsFile{Deps: []godepsDep{}}
+	for _, pkgDep := range allDeps(depsFile) {
+		godeps.Deps = append(godeps.Deps, godepsDep{ImportPath: pkgDep.ImportPath, Comment: firstTag(pkgDep), Rev: pkgDep.SHA})
+	}
+
+	jsonOutput, err := json.MarshalIndent(&godeps, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return writeOutput(filename, append(jsonOutput, '\n'))
+}
@@ -0,0 +1,106 @@
+package snapshot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	semverTagRe   = regexp.MustCompile(`^v([0-9]+)\.[0-9]+\.[0-9]+(-[0-9A-Za-z.-]+)?$`)
+	majorSuffixRe = regexp.MustCompile(`/v([2-9][0-9]*)$`)
+)
+
+//GoModVersion returns the version to require pkgDep at in a go.mod file. This
+//is its module version if it was snapshotted as a module, a semver tag on the
+//pinned commit if there is one, otherwise a pseudo-version derived from the
+//SHA and CommitTime.
+func GoModVersion(pkgDep PkgDep) (string, error) {
+	if version := pkgDep.moduleVersion(); version != "" {
+		return version, nil
+	}
+
+	major := 0
+	if m := majorSuffixRe.FindStringSubmatch(pkgDep.ImportPath); m != nil {
+		major, _ = strconv.Atoi(m[1])
+	}
+
+	//The highest tag for the major version, where without a major version
+	//suffix v0 and v1 tags are preferred to any +incompatible one
+	best, bestIncompatible := "", ""
+	higher := func(tag, than string) bool {
+		if than == "" {
+			return true
+		}
+		v, _ := parseSemver(tag)
+		other, _ := parseSemver(than)
+		return v.compare(other) > 0
+	}
+	for _, tag := range pkgDep.Tags {
+		m := semverTagRe.FindStringSubmatch(tag)
+		if m == nil {
+			continue
+		}
+		tagMajor, _ := strconv.Atoi(m[1])
+		if (major != 0 && tagMajor == major) || (major == 0 && tagMajor <= 1) {
+			if higher(tag, best) {
+				best = tag
+			}
+		} else if major == 0 && higher(tag, bestIncompatible) {
+			bestIncompatible = tag
+		}
+	}
+	if best != "" {
+		return best, nil
+	} else if bestIncompatible != "" {
+		return bestIncompatible + "+incompatible", nil
+	}
+
+	if len(pkgDep.SHA) < 12 || pkgDep.CommitTime.IsZero() {
+		return "", fmt.Errorf("Can not derive a version for %s without a SHA and commit time.", pkgDep.ImportPath)
+	}
+
+	return fmt.Sprintf("v%d.0.0-%s-%s", major, pkgDep.CommitTime.UTC().Format("20060102150405"), pkgDep.SHA[0:12]), nil
+}
+
+//WriteGoMod writes a go.mod require block for every dependency in depsFile.
+//Dependencies a version can't be found for are left out and reported in the
+//returned error.
+func WriteGoMod(filename string, depsFile DepsFile) error {
+	requires := map[string]string{}
+	errStrings := []string{}
+	for _, pkgDep := range allDeps(depsFile) {
+		version, err := GoModVersion(pkgDep)
+		if err != nil {
+			errStrings = append(errStrings, err.Error())
+			continue
+		}
+		requires[pkgDep.ImportPath] = version
+	}
+
+	importPaths := []string{}
+	for importPath, _ := range requires {
+		importPaths = append(importPaths, importPath)
+	}
+	sort.Strings(importPaths)
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("require (\n")
+	for _, importPath := range importPaths {
+		fmt.Fprintf(buf, "\t%s %s\n", importPath, requires[importPath])
+	}
+	buf.WriteString(")\n")
+
+	if err := writeOutput(filename, buf.Bytes()); err != nil {
+		return err
+	}
+
+	if len(errStrings) != 0 {
+		return errors.New(strings.Join(errStrings, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package snapshot_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/desal/go-snap/snapshot"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoModVersion(t *testing.T) {
+	commitTime := time.Date(2017, 3, 4, 15, 16, 17, 0, time.FixedZone("AEST", 10*60*60))
+	sha := "0123456789abcdef0123456789abcdef01234567"
+
+	for _, c := range []struct {
+		pkgDep   snapshot.PkgDep
+		expected string
+	}{
+		{snapshot.PkgDep{ImportPath: "example.com/a", SHA: sha, CommitTime: commitTime}, "v0.0.0-20170304051617-0123456789ab"},
+		{snapshot.PkgDep{ImportPath: "example.com/a/v3", SHA: sha, CommitTime: commitTime}, "v3.0.0-20170304051617-0123456789ab"},
+		{snapshot.PkgDep{ImportPath: "example.com/a", SHA: sha, CommitTime: commitTime, Tags: []string{"v1.0", "v1.2.3"}}, "v1.2.3"},
+		{snapshot.PkgDep{ImportPath: "example.com/a", SHA: sha, CommitTime: commitTime, Tags: []string{"v2.0.1"}}, "v2.0.1+incompatible"},
+		{snapshot.PkgDep{ImportPath: "example.com/a/v2", SHA: sha, CommitTime: commitTime, Tags: []string{"v2.0.1"}}, "v2.0.1"},
+		{snapshot.PkgDep{ImportPath: "example.com/a", SHA: sha, CommitTime: commitTime, Tags: []string{"v10.0.0", "v2.0.0", "v1.9.0"}}, "v1.9.0"},
+		{snapshot.PkgDep{ImportPath: "example.com/a", SHA: sha, CommitTime: commitTime, Tags: []string{"v10.0.0", "v2.0.0"}}, "v10.0.0+incompatible"},
+		{snapshot.PkgDep{ImportPath: "example.com/a", SHA: sha, CommitTime: commitTime, Tags: []string{"v1.10.0", "v1.9.0", "v1.10.0-rc.1"}}, "v1.10.0"},
+		{snapshot.PkgDep{ImportPath: "example.com/a/v2", SHA: sha, CommitTime: commitTime, Tags: []string{"v1.0.0", "v2.1.0", "v2.0.9"}}, "v2.1.0"},
+		{snapshot.PkgDep{ImportPath: "example.com/a", Module: &snapshot.Module{Path: "example.com/a", Version: "v1.4.0"}}, "v1.4.0"},
+	} {
+		version, err := snapshot.GoModVersion(c.pkgDep)
+		require.Nil(t, err)
+		assert.Equal(t, c.expected, version)
+	}
+
+	_, err := snapshot.GoModVersion(snapshot.PkgDep{ImportPath: "example.com/a", SHA: sha})
+	assert.NotNil(t, err)
+}
+
+func TestWriteGoMod(t *testing.T) {
+	commitTime := time.Date(2017, 3, 4, 5, 16, 17, 0, time.UTC)
+	depsFile := snapshot.DepsFile{
+		Deps: []snapshot.PkgDep{
+			snapshot.PkgDep{ImportPath: "example.com/b", SHA: "0123456789abcdef", CommitTime: commitTime},
+			snapshot.PkgDep{ImportPath: "example.com/a", Tags: []string{"v1.0.0"}},
+			snapshot.PkgDep{ImportPath: "example.com/broken"},
+		},
+		TestDeps: []snapshot.PkgDep{
+			snapshot.PkgDep{ImportPath: "example.com/c", Tags: []string{"v0.1.0"}},
+		},
+	}
+
+	f, err := ioutil.TempFile("", "gomod_test")
+	require.Nil(t, err)
+	f.Close()
+	defer os.Remove(f.Name())
+
+	err = snapshot.WriteGoMod(f.Name(), depsFile)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "example.com/broken")
+
+	output, err := ioutil.ReadFile(f.Name())
+	require.Nil(t, err)
+	assert.Equal(t, `require (
+	example.com/a v1.0.0
+	example.com/b v0.0.0-20170304051617-0123456789ab
+	example.com/c v0.1.0
+)
+`, string(output))
+}
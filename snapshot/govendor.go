@@ -0,0 +1,76 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type govendorFile struct {
+	Comment  string            `json:"comment"`
+	Ignore   string            `json:"ignore"`
+	Package  []govendorPackage `json:"package"`
+	RootPath string            `json:"rootPath"`
+}
+
+type govendorPackage struct {
+	ChecksumSHA1 string `json:"checksumSHA1,omitempty"`
+	Path         string `json:"path"`
+	Origin       string `json:"origin,omitempty"`
+	Revision     string `json:"revision"`
+	RevisionTime string `json:"revisionTime,omitempty"`
+	Version      string `json:"version,omitempty"`
+	VersionExact string `json:"versionExact,omitempty"`
+}
+
+//ImportGovendor reads a vendor/vendor.json file from govendor.
+func ImportGovendor(filename string) (DepsFile, error) {
+	var govendor govendorFile
+
+	r, err := openInput(filename)
+	if err != nil {
+		return DepsFile{}, err
+	}
+	defer r.Close()
+
+	if err := json.NewDecoder(r).Decode(&govendor); err != nil {
+		return DepsFile{}, err
+	}
+
+	pkgDeps := []PkgDep{}
+	for _, pkg := range govendor.Package {
+		pkgDep := PkgDep{ImportPath: pkg.Path, SHA: pkg.Revision}
+		if pkg.RevisionTime != "" {
+			pkgDep.CommitTime, err = time.Parse(time.RFC3339, pkg.RevisionTime)
+			if err != nil {
+				return DepsFile{}, err
+			}
+		}
+		if isTag(pkg.VersionExact) {
+			pkgDep.Tags = []string{pkg.VersionExact}
+		}
+		pkgDeps = append(pkgDeps, pkgDep)
+	}
+
+	deps, err := groupPackages(pkgDeps)
+	return DepsFile{Deps: deps, TestDeps: []PkgDep{}}, err
+}
+
+//WriteGovendor writes depsFile as a vendor.json file. govendor doesn't keep
+//test dependencies separate, so they are listed with the rest.
+func WriteGovendor(filename string, depsFile DepsFile) error {
+	govendor := govendorFile{Ignore: "test", Package: []govendorPackage{}}
+	for _, pkgDep := range allDeps(depsFile) {
+		pkg := govendorPackage{Path: pkgDep.ImportPath, Revision: pkgDep.SHA, VersionExact: firstTag(pkgDep)}
+		if !pkgDep.CommitTime.IsZero() {
+			pkg.RevisionTime = pkgDep.CommitTime.UTC().Format(time.RFC3339)
+		}
+		govendor.Package = append(govendor.Package, pkg)
+	}
+
+	jsonOutput, err := json.MarshalIndent(&govendor, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return writeOutput(filename, append(jsonOutput, '\n'))
+}
@@ -0,0 +1,168 @@
+package snapshot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//GraphFormat is a format the import graph can be written in.
+type GraphFormat string
+
+const (
+	GraphFormat_Dot  GraphFormat = "dot"
+	GraphFormat_Json GraphFormat = "json"
+	GraphFormat_Tree GraphFormat = "tree"
+)
+
+//Graph is the import graph between repositories, recorded by Snapshot with
+//the ImportGraph flag. Each repository is named by its root import path, as
+//in PkgDep, and Roots are those snapshotted, sorted.
+type Graph struct {
+	Roots   []string
+	Imports map[string][]string //Every repository, to those it imports
+}
+
+//newGraph builds the Graph of depsFile from the imports of each package
+//listed. Each of initialPackages is in the longest of roots containing it.
+//Packages not in a root or dependency, such as the standard library, are
+//left out.
+func newGraph(roots stringSet, pkgImports map[string]stringSet, initialPackages stringSet, depsFile DepsFile) *Graph {
+	pkgDeps := append(append([]PkgDep{}, depsFile.Deps...), depsFile.TestDeps...)
+	repo := func(pkg string) string {
+		r := ""
+		if _, isInitial := initialPackages[pkg]; isInitial {
+			for root := range roots {
+				if pkgContains(root, pkg) && len(root) > len(r) {
+					r = root
+				}
+			}
+			return r
+		}
+		for _, pkgDep := range pkgDeps {
+			if pkgContains(pkgDep.ImportPath, pkg) && len(pkgDep.ImportPath) > len(r) {
+				r = pkgDep.ImportPath
+			}
+		}
+		return r
+	}
+
+	edges := map[string]stringSet{}
+	for root := range roots {
+		edges[root] = stringSet{}
+	}
+	for _, pkgDep := range pkgDeps {
+		edges[pkgDep.ImportPath] = stringSet{}
+	}
+	for pkg, imports := range pkgImports {
+		from := repo(pkg)
+		if from == "" {
+			continue
+		}
+		for imported := range imports {
+			if to := repo(imported); to != "" && to != from {
+				edges[from][to] = empty{}
+			}
+		}
+	}
+
+	r := &Graph{Roots: roots.Sorted(), Imports: map[string][]string{}}
+	for from, to := range edges {
+		r.Imports[from] = to.Sorted()
+	}
+	return r
+}
+
+//nodes is every repository in the graph, sorted.
+func (g Graph) nodes() []string {
+	r := []string{}
+	for node := range g.Imports {
+		r = append(r, node)
+	}
+	sort.Strings(r)
+	return r
+}
+
+//WriteGraph writes graph to filename in the given format.
+func WriteGraph(filename string, graph Graph, format GraphFormat) error {
+	switch format {
+	case GraphFormat_Dot:
+		return WriteGraphDot(filename, graph)
+	case GraphFormat_Json:
+		return WriteGraphJson(filename, graph)
+	case GraphFormat_Tree:
+		return WriteGraphTree(filename, graph)
+	}
+	return fmt.Errorf("Unknown graph format %s.", format)
+}
+
+//WriteGraphDot writes graph for Graphviz, with an edge from each repository
+//to each it imports. Roots are drawn as boxes.
+func WriteGraphDot(filename string, graph Graph) error {
+	roots := stringSet{}
+	for _, root := range graph.Roots {
+		roots[root] = empty{}
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "digraph deps {\n")
+	for _, node := range graph.nodes() {
+		if _, isRoot := roots[node]; isRoot {
+			fmt.Fprintf(buf, "  %q [shape=box];\n", node)
+		} else {
+			fmt.Fprintf(buf, "  %q;\n", node)
+		}
+	}
+	for _, node := range graph.nodes() {
+		for _, imported := range graph.Imports[node] {
+			fmt.Fprintf(buf, "  %q -> %q;\n", node, imported)
+		}
+	}
+	fmt.Fprintf(buf, "}\n")
+
+	return writeOutput(filename, buf.Bytes())
+}
+
+//WriteGraphJson writes graph as its roots and an adjacency list.
+func WriteGraphJson(filename string, graph Graph) error {
+	jsonOutput, err := json.MarshalIndent(&graph, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeOutput(filename, jsonOutput)
+}
+
+//WriteGraphTree writes graph as a tree indented by depth from each of its
+//roots in turn. A repository already shown is not expanded again.
+func WriteGraphTree(filename string, graph Graph) error {
+	buf := &bytes.Buffer{}
+	shown := stringSet{}
+	var writeNode func(node string, depth int)
+	writeNode = func(node string, depth int) {
+		indent := strings.Repeat("  ", depth)
+		if _, isShown := shown[node]; isShown && len(graph.Imports[node]) != 0 {
+			fmt.Fprintf(buf, "%s%s (see above)\n", indent, node)
+			return
+		}
+		shown[node] = empty{}
+		fmt.Fprintf(buf, "%s%s\n", indent, node)
+		for _, imported := range graph.Imports[node] {
+			writeNode(imported, depth+1)
+		}
+	}
+	for _, root := range graph.Roots {
+		writeNode(root, 0)
+	}
+
+	//Any not reachable from a root, if some packages could not be listed
+	for _, node := range graph.nodes() {
+		if _, isShown := shown[node]; !isShown {
+			writeNode(node, 0)
+		}
+	}
+
+	return writeOutput(filename, buf.Bytes())
+}
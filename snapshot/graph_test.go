@@ -0,0 +1,164 @@
+package snapshot_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/desal/go-snap/snapshot"
+	"github.com/desal/richtext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotGraph(t *testing.T) {
+	m := SetupRepos(t)
+	defer m.Close()
+
+	m.AddRepo("depone")
+	m.AddRepo("deptwo")
+	m.AddRepo("deptest")
+	m.AddRepo("mainpkg")
+
+	m.goCtx.Execf(`
+		cd src/deptwo;
+		echo 'package deptwo\n\nconst Two = 3' > deptwo.go;
+		git add -A;
+		git commit -m "gocode";
+		git push`)
+	m.goCtx.Execf(`
+		cd src/deptest;
+		echo 'package deptest\n\nconst Test = 4' > deptest.go;
+		git add -A;
+		git commit -m "gocode";
+		git push`)
+	m.goCtx.Execf(`
+		cd src/depone;
+		mkdir sub;
+		echo 'package sub\n\nimport "deptwo"\n\nconst One = deptwo.Two' > sub/sub.go;
+		echo 'package depone\n\nimport "depone/sub"\n\nconst One = sub.One' > depone.go;
+		git add -A;
+		git commit -m "gocode";
+		git push`)
+	m.goCtx.Execf(`
+		cd src/mainpkg;
+		echo 'package main\n\nimport (\n\t"depone"\n\t"deptwo"\n)\n\nvar _ = depone.One * deptwo.Two' > main.go;
+		echo 'package main\n\nimport (\n\t"deptest"\n\t"testing"\n)\n\nfunc TestMain(t *testing.T) { _ = deptest.Test }' > main_test.go;
+		git add -A;
+		git commit -m "gocode";
+		git push`)
+
+	ctx := snapshot.New(richtext.Test(t), []string{m.gopath})
+	depsFile, err := ctx.Snapshot(m.gopath, "mainpkg", []string{""})
+	require.Nil(t, err)
+	assert.Nil(t, depsFile.Graph)
+
+	ctx = snapshot.New(richtext.Test(t), []string{m.gopath}, snapshot.ImportGraph)
+	depsFile, err = ctx.Snapshot(m.gopath, "mainpkg", []string{""})
+	require.Nil(t, err)
+	require.NotNil(t, depsFile.Graph)
+	assert.Equal(t, snapshot.Graph{
+		Roots: []string{"mainpkg"},
+		Imports: map[string][]string{
+			"mainpkg": {"depone", "deptest", "deptwo"},
+			"depone":  {"deptwo"},
+			"deptwo":  {},
+			"deptest": {},
+		},
+	}, *depsFile.Graph)
+
+	tempDir, err := ioutil.TempDir("", "snapshot_test_graph")
+	require.Nil(t, err)
+	defer os.RemoveAll(tempDir)
+
+	output := func(format snapshot.GraphFormat) string {
+		filename := filepath.Join(tempDir, string(format))
+		require.Nil(t, snapshot.WriteGraph(filename, *depsFile.Graph, format))
+		b, err := ioutil.ReadFile(filename)
+		require.Nil(t, err)
+		return string(b)
+	}
+
+	assert.Equal(t, "mainpkg\n"+
+		"  depone\n"+
+		"    deptwo\n"+
+		"  deptest\n"+
+		"  deptwo\n", output(snapshot.GraphFormat_Tree))
+
+	assert.Equal(t, "digraph deps {\n"+
+		"  \"depone\";\n"+
+		"  \"deptest\";\n"+
+		"  \"deptwo\";\n"+
+		"  \"mainpkg\" [shape=box];\n"+
+		"  \"depone\" -> \"deptwo\";\n"+
+		"  \"mainpkg\" -> \"depone\";\n"+
+		"  \"mainpkg\" -> \"deptest\";\n"+
+		"  \"mainpkg\" -> \"deptwo\";\n"+
+		"}\n", output(snapshot.GraphFormat_Dot))
+
+	assert.Contains(t, output(snapshot.GraphFormat_Json), `"depone": [
+      "deptwo"
+    ],`)
+
+	assert.EqualError(t, snapshot.WriteGraph(filepath.Join(tempDir, "svg"), *depsFile.Graph, "svg"), "Unknown graph format svg.")
+
+	//Each package snapshotted is in its own repository
+	m.AddRepo("maintwo")
+	m.goCtx.Execf(`
+		cd src/maintwo;
+		echo 'package main\n\nimport "deptwo"\n\nvar _ = deptwo.Two' > main.go;
+		git add -A;
+		git commit -m "gocode";
+		git push`)
+
+	ctx = snapshot.New(richtext.Test(t), []string{m.gopath}, snapshot.ImportGraph)
+	depsFile, err = ctx.Snapshot(m.gopath, "mainpkg maintwo", []string{""})
+	require.Nil(t, err)
+	assert.Equal(t, snapshot.Graph{
+		Roots: []string{"mainpkg", "maintwo"},
+		Imports: map[string][]string{
+			"mainpkg": {"depone", "deptest", "deptwo"},
+			"maintwo": {"deptwo"},
+			"depone":  {"deptwo"},
+			"deptwo":  {},
+			"deptest": {},
+		},
+	}, *depsFile.Graph)
+
+	assert.Equal(t, "mainpkg\n"+
+		"  depone\n"+
+		"    deptwo\n"+
+		"  deptest\n"+
+		"  deptwo\n"+
+		"maintwo\n"+
+		"  deptwo\n", output(snapshot.GraphFormat_Tree))
+	assert.Contains(t, output(snapshot.GraphFormat_Dot), "  \"maintwo\" [shape=box];\n")
+}
+
+func TestGraphTreeRepeats(t *testing.T) {
+	graph := snapshot.Graph{
+		Roots: []string{"root"},
+		Imports: map[string][]string{
+			"root": {"a", "b"},
+			"a":    {"c"},
+			"b":    {"a"},
+			"c":    {"a"},
+		},
+	}
+
+	filename, err := ioutil.TempFile("", "snapshot_test_graph")
+	require.Nil(t, err)
+	filename.Close()
+	defer os.Remove(filename.Name())
+
+	require.Nil(t, snapshot.WriteGraphTree(filename.Name(), graph))
+	b, err := ioutil.ReadFile(filename.Name())
+	require.Nil(t, err)
+	assert.Equal(t, "root\n"+
+		"  a\n"+
+		"    c\n"+
+		"      a (see above)\n"+
+		"  b\n"+
+		"    a (see above)\n", string(b))
+}
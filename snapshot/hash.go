@@ -0,0 +1,195 @@
+package snapshot
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/desal/cmd"
+)
+
+//fileHashes maps the slash separated name of every file in a tree to the
+//SHA-256 of its contents, or of its target for symlinks.
+type fileHashes map[string]string
+
+//treeFile is a file tracked in a git commit. Content is the target of a
+//symlink.
+type treeFile struct {
+	name    string //Slash separated, relative to the repository root
+	mode    string //As in git ls-tree, such as 100644 or 120000
+	content []byte
+}
+
+//sum is a deterministic hash of the whole tree, the base64 SHA-256 of the
+//sorted list of file hashes and names. It is not the go.sum dirhash.
+func (f fileHashes) sum() string {
+	names := []string{}
+	for name, _ := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(h, "%s  %s\n", f[name], name)
+	}
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func hashReader(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+//commitFiles calls fn with every file tracked at sha in the git repository
+//dir, which may be bare. The blobs are read as stored, unlike git archive,
+//so .gitattributes export-ignore and export-subst don't apply. Submodules
+//are left out.
+func (c *Context) commitFiles(dir, sha string, fn func(file treeFile) error) error {
+	tree, err := c.gitExecf(dir, "ls-tree -r -z '%s'", sha)
+	if err != nil {
+		return err
+	}
+
+	files := []treeFile{}
+	objects := &bytes.Buffer{}
+	for _, entry := range strings.Split(tree, "\x00") {
+		//100644 blob 0123abc\tname
+		parts := strings.SplitN(entry, "\t", 2)
+		fields := strings.Fields(parts[0])
+		if len(parts) != 2 || len(fields) != 3 || fields[1] != "blob" {
+			continue
+		}
+		files = append(files, treeFile{name: parts[1], mode: fields[0]})
+		fmt.Fprintf(objects, "%s\n", fields[2])
+	}
+	if len(files) == 0 {
+		return nil
+	}
+
+	objectList, err := ioutil.TempFile("", "go-snap-objects")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(objectList.Name())
+	_, err = objectList.Write(objects.Bytes())
+	objectList.Close()
+	if err != nil {
+		return err
+	}
+
+	//Not vcsExecf, which would trim whitespace from the last blob
+	stdout, stderr, err := cmd.New(dir, c.format).Execf("git cat-file --batch < '%s'", objectList.Name())
+	if err != nil {
+		return fmt.Errorf("%s: %s", err.Error(), strings.TrimSpace(stderr))
+	}
+
+	//Each blob is a "<sha> blob <size>" line, its content and a newline
+	output := []byte(stdout)
+	for i := range files {
+		eol := bytes.IndexByte(output, '\n')
+		if eol < 0 {
+			return fmt.Errorf("Unexpected end of git cat-file output for %s.", files[i].name)
+		}
+		fields := strings.Fields(string(output[:eol]))
+		if len(fields) != 3 {
+			return fmt.Errorf("Unexpected git cat-file output '%s' for %s.", string(output[:eol]), files[i].name)
+		}
+		size, err := strconv.Atoi(fields[2])
+		if err != nil || eol+1+size > len(output) {
+			return fmt.Errorf("Unexpected git cat-file output '%s' for %s.", string(output[:eol]), files[i].name)
+		}
+		files[i].content = output[eol+1 : eol+1+size]
+		output = output[eol+1+size:]
+		if len(output) > 0 && output[0] == '\n' {
+			output = output[1:]
+		}
+
+		if err := fn(files[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//commitHash hashes the files tracked at sha in the git repository dir.
+func (c *Context) commitHash(dir, sha string) (string, error) {
+	return c.treeHash(dir, sha, func(string) bool { return true })
+}
+
+//treeHash is commitHash of only the files keep is true for.
+func (c *Context) treeHash(dir, sha string, keep func(name string) bool) (string, error) {
+	hashes := fileHashes{}
+	err := c.commitFiles(dir, sha, func(file treeFile) error {
+		if !keep(file.name) {
+			return nil
+		}
+		var err error
+		hashes[file.name], err = hashReader(bytes.NewReader(file.content))
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return hashes.sum(), nil
+}
+
+//dirHash hashes every file under dir as it is on disk, other than git
+//metadata and the slash separated subdirectories in exclude, which belong to
+//other dependencies.
+func dirHash(dir string, exclude stringSet) (string, error) {
+	hashes := fileHashes{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+
+		if info.IsDir() {
+			if _, excluded := exclude[name]; excluded || name == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			hashes[name], err = hashReader(strings.NewReader(target))
+			return err
+		} else if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		hashes[name], err = hashReader(f)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return hashes.sum(), nil
+}
@@ -0,0 +1,130 @@
+package snapshot
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/desal/git"
+)
+
+//hgVCS is Mercurial, where the default branch is named default.
+type hgVCS struct {
+	c *Context
+}
+
+func (v *hgVCS) execf(dir, format string, a ...interface{}) (string, error) {
+	return v.c.vcsExecf(dir, "hg", format, a...)
+}
+
+func (v *hgVCS) Name() string { return "hg" }
+
+func (v *hgVCS) IsRepo(dir string) bool {
+	_, err := v.execf(dir, "root")
+	return err == nil
+}
+
+func (v *hgVCS) Status(dir, branch string) (git.Status, error) {
+	status, err := v.execf(dir, "status")
+	if err != nil {
+		return git.Clean, err
+	} else if status != "" {
+		return git.Uncommitted, nil
+	}
+
+	revision, err := v.Revision(dir)
+	if err != nil {
+		return git.Clean, err
+	}
+	if branch == "" {
+		branch = "default"
+	}
+	branchRevision, err := v.execf(dir, "log -r '%s' --template '{node}'", branch)
+	if err != nil || revision != branchRevision {
+		return git.NotMaster, nil
+	}
+	return git.Clean, nil
+}
+
+func (v *hgVCS) Branch(dir string) (string, error) {
+	return v.execf(dir, "branch")
+}
+
+func (v *hgVCS) DefaultBranch(dir string) (string, error) {
+	return "default", nil
+}
+
+func (v *hgVCS) TopLevel(dir string) (string, error) {
+	return v.execf(dir, "root")
+}
+
+func (v *hgVCS) RemoteUrl(dir string) (string, error) {
+	return v.execf(dir, "paths default")
+}
+
+func (v *hgVCS) Revision(dir string) (string, error) {
+	return v.execf(dir, "log -r . --template '{node}'")
+}
+
+func (v *hgVCS) CommitTime(dir string) (time.Time, error) {
+	output, err := v.execf(dir, "log -r . --template '{date|rfc3339date}'")
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339, output)
+}
+
+func (v *hgVCS) Tags(dir string) ([]string, error) {
+	output, err := v.execf(dir, "log -r . --template '{tags}'")
+	if err != nil {
+		return nil, err
+	}
+
+	r := []string{}
+	for _, tag := range strings.Fields(output) {
+		if tag != "tip" {
+			r = append(r, tag)
+		}
+	}
+	sort.Strings(r)
+	return r, nil
+}
+
+func (v *hgVCS) TagRevisions(dir string) (map[string]string, error) {
+	output, err := v.execf(dir, "tags --debug")
+	if err != nil {
+		return nil, err
+	}
+
+	r := map[string]string{}
+	for _, line := range strings.Split(output, "\n") {
+		//v1.0.0                             3:0123abc...
+		fields := strings.Fields(line)
+		if len(fields) != 2 || fields[0] == "tip" {
+			continue
+		}
+		if i := strings.Index(fields[1], ":"); i != -1 {
+			r[fields[0]] = fields[1][i+1:]
+		}
+	}
+	return r, nil
+}
+
+func (v *hgVCS) Clone(dir, remote string) error {
+	return v.c.cloneExecf(dir, "hg", "clone -q '%s' '%s'", remote, dir)
+}
+
+func (v *hgVCS) Checkout(dir, revision string) error {
+	_, err := v.execf(dir, "update -q -r '%s'", revision)
+	return err
+}
+
+func (v *hgVCS) Pull(dir, branch string) error {
+	if _, err := v.execf(dir, "pull -q"); err != nil {
+		return err
+	}
+	if branch == "" {
+		branch = "default"
+	}
+	return v.Checkout(dir, branch)
+}
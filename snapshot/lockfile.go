@@ -0,0 +1,185 @@
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+//LockFormat is a file format a DepsFile can be converted to or from, either
+//go-snap's own or the lock file of another dependency manager.
+type LockFormat string
+
+const (
+	LockFormat_Json     LockFormat = "json"
+	LockFormat_GoMod    LockFormat = "gomod"
+	LockFormat_Godeps   LockFormat = "godeps"
+	LockFormat_Glide    LockFormat = "glide"
+	LockFormat_Dep      LockFormat = "dep"
+	LockFormat_Govendor LockFormat = "govendor"
+)
+
+var (
+	lockFilenames = map[string]LockFormat{
+		"Godeps.json": LockFormat_Godeps,
+		"glide.lock":  LockFormat_Glide,
+		"Gopkg.lock":  LockFormat_Dep,
+		"vendor.json": LockFormat_Govendor,
+	}
+
+	//Where each format is written by default, relative to the project
+	defaultLockFilenames = map[LockFormat]string{
+		LockFormat_Json:     "snapshot.json",
+		LockFormat_GoMod:    "go.mod",
+		LockFormat_Godeps:   "Godeps/Godeps.json",
+		LockFormat_Glide:    "glide.lock",
+		LockFormat_Dep:      "Gopkg.lock",
+		LockFormat_Govendor: "vendor/vendor.json",
+	}
+
+	//Number of path elements in a repository root for the hosts go get knows
+	//without looking for meta tags.
+	knownHosts = map[string]int{
+		"github.com":    3,
+		"bitbucket.org": 3,
+		"gitlab.com":    3,
+		"golang.org":    3,
+		"launchpad.net": 2,
+	}
+
+	gopkgInRe  = regexp.MustCompile(`^gopkg\.in/([^/]+/)?[^/]+\.v[0-9]+`)
+	describeRe = regexp.MustCompile(`-[0-9]+-g[0-9a-f]+$`)
+)
+
+//LockFormatOf guesses the format of a lockfile from its name.
+func LockFormatOf(filename string) (LockFormat, error) {
+	if format, ok := lockFilenames[filepath.Base(filename)]; ok {
+		return format, nil
+	}
+	return "", fmt.Errorf("Can not tell the lock file format of %s.", filename)
+}
+
+//LockFilename is where the given format is written by default, such as
+//Gopkg.lock for dep. It is an error for format to be unknown.
+func LockFilename(format LockFormat) (string, error) {
+	if filename, ok := defaultLockFilenames[format]; ok {
+		return filepath.FromSlash(filename), nil
+	}
+	return "", fmt.Errorf("Unknown lock file format %s.", format)
+}
+
+//Import converts a lockfile of the given format into a DepsFile.
+func Import(filename string, format LockFormat) (DepsFile, error) {
+	switch format {
+	case LockFormat_Json:
+		return ReadJson(filename)
+	case LockFormat_Godeps:
+		return ImportGodeps(filename)
+	case LockFormat_Glide:
+		return ImportGlide(filename)
+	case LockFormat_Dep:
+		return ImportDep(filename)
+	case LockFormat_Govendor:
+		return ImportGovendor(filename)
+	}
+	return DepsFile{}, fmt.Errorf("Unknown lock file format %s.", format)
+}
+
+//Export writes depsFile in the given format.
+func Export(filename string, depsFile DepsFile, format LockFormat) error {
+	switch format {
+	case LockFormat_Json:
+		return WriteJson(filename, depsFile)
+	case LockFormat_GoMod:
+		return WriteGoMod(filename, depsFile)
+	case LockFormat_Godeps:
+		return WriteGodeps(filename, depsFile)
+	case LockFormat_Glide:
+		return WriteGlide(filename, depsFile)
+	case LockFormat_Dep:
+		return WriteDep(filename, depsFile)
+	case LockFormat_Govendor:
+		return WriteGovendor(filename, depsFile)
+	}
+	return fmt.Errorf("Unknown lock file format %s.", format)
+}
+
+//repoRoot is the repository root of importPath for the well known hosts,
+//otherwise importPath itself.
+func repoRoot(importPath string) string {
+	if m := gopkgInRe.FindString(importPath); m != "" {
+		return m
+	}
+
+	elements := strings.Split(importPath, "/")
+	if n, ok := knownHosts[elements[0]]; ok && len(elements) >= n {
+		return strings.Join(elements[:n], "/")
+	}
+	return importPath
+}
+
+//allDeps is every dependency in depsFile, for formats that don't keep test
+//dependencies separate.
+func allDeps(depsFile DepsFile) []PkgDep {
+	r := append(append([]PkgDep{}, depsFile.Deps...), depsFile.TestDeps...)
+	sort.Sort(PkgDepsByImport(r))
+	return r
+}
+
+//firstTag is the tag recorded in formats that only keep one.
+func firstTag(pkgDep PkgDep) string {
+	if len(pkgDep.Tags) == 0 {
+		return ""
+	}
+	return pkgDep.Tags[0]
+}
+
+//repoRemote is the remote go get would clone the repository at root from.
+func repoRemote(root string) string {
+	if strings.HasPrefix(root, "golang.org/x/") {
+		return "https://go.googlesource.com/" + strings.TrimPrefix(root, "golang.org/x/")
+	}
+	return "https://" + root
+}
+
+//isTag is false for the output of git describe, which some lock files keep
+//in the same field as a tag.
+func isTag(version string) bool {
+	return version != "" && !describeRe.MatchString(version)
+}
+
+//groupPackages collapses lock files that record every package into one
+//PkgDep per repository, as Snapshot records them.
+func groupPackages(pkgDeps []PkgDep) ([]PkgDep, error) {
+	sort.Sort(PkgDepsByImport(pkgDeps))
+
+	r := []PkgDep{}
+	errStrings := []string{}
+	for _, pkgDep := range pkgDeps {
+		root := repoRoot(pkgDep.ImportPath)
+
+		if len(r) != 0 {
+			last := r[len(r)-1]
+			if pkgContains(last.ImportPath, root) || pkgContains(last.ImportPath, pkgDep.ImportPath) {
+				if last.SHA != pkgDep.SHA {
+					errStrings = append(errStrings, fmt.Sprintf("%s is locked at %s but %s is locked at %s", last.ImportPath, last.SHA, pkgDep.ImportPath, pkgDep.SHA))
+				}
+				continue
+			}
+		}
+
+		pkgDep.ImportPath = root
+		if pkgDep.GitRemote == "" {
+			pkgDep.GitRemote = repoRemote(root)
+		}
+		r = append(r, pkgDep)
+	}
+
+	if len(errStrings) != 0 {
+		return r, errors.New(strings.Join(errStrings, ", "))
+	}
+	return r, nil
+}
@@ -0,0 +1,240 @@
+package snapshot_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/desal/go-snap/snapshot"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeLockFile(t *testing.T, name, contents string) (string, func()) {
+	dir, err := ioutil.TempDir("", "lockfile_test")
+	require.Nil(t, err)
+	filename := filepath.Join(dir, name)
+	require.Nil(t, ioutil.WriteFile(filename, []byte(contents), 0644))
+	return filename, func() { os.RemoveAll(dir) }
+}
+
+func TestImportGodeps(t *testing.T) {
+	filename, cleanup := writeLockFile(t, "Godeps.json", `{
+	"ImportPath": "example.com/mainpkg",
+	"GoVersion": "go1.8",
+	"Deps": [
+		{"ImportPath": "github.com/desal/git", "Rev": "1111111111111111111111111111111111111111"},
+		{"ImportPath": "github.com/stretchr/testify/assert", "Comment": "v1.1.4", "Rev": "2222222222222222222222222222222222222222"},
+		{"ImportPath": "github.com/stretchr/testify/require", "Comment": "v1.1.4", "Rev": "2222222222222222222222222222222222222222"},
+		{"ImportPath": "golang.org/x/net/context", "Comment": "v0.1-3-gabcdef0", "Rev": "3333333333333333333333333333333333333333"}
+	]
+}`)
+	defer cleanup()
+
+	format, err := snapshot.LockFormatOf(filename)
+	require.Nil(t, err)
+	depsFile, err := snapshot.Import(filename, format)
+	require.Nil(t, err)
+
+	assert.Equal(t, "{["+
+		"{github.com/desal/git  https://github.com/desal/git 1111111111111111111111111111111111111111  0001-01-01 00:00:00 +0000 UTC []  <nil> [] <nil>} "+
+		"{github.com/stretchr/testify  https://github.com/stretchr/testify 2222222222222222222222222222222222222222  0001-01-01 00:00:00 +0000 UTC [v1.1.4]  <nil> [] <nil>} "+
+		"{golang.org/x/net  https://go.googlesource.com/net 3333333333333333333333333333333333333333  0001-01-01 00:00:00 +0000 UTC []  <nil> [] <nil>}"+
+		"] [] <nil>}", fmt.Sprintf("%v", depsFile))
+}
+
+func TestImportGodepsConflict(t *testing.T) {
+	filename, cleanup := writeLockFile(t, "Godeps.json", `{
+	"Deps": [
+		{"ImportPath": "github.com/stretchr/testify/assert", "Rev": "2222222222222222222222222222222222222222"},
+		{"ImportPath": "github.com/stretchr/testify/require", "Rev": "4444444444444444444444444444444444444444"}
+	]
+}`)
+	defer cleanup()
+
+	_, err := snapshot.ImportGodeps(filename)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "github.com/stretchr/testify/require is locked at 4444444444444444444444444444444444444444")
+}
+
+func TestImportGlide(t *testing.T) {
+	filename, cleanup := writeLockFile(t, "glide.lock", `hash: 0123456789abcdef
+updated: 2017-03-04T15:16:17.000000000+10:00
+imports:
+- name: github.com/desal/git
+  version: 1111111111111111111111111111111111111111
+- name: gopkg.in/yaml.v2
+  version: 2222222222222222222222222222222222222222
+  repo: https://github.com/go-yaml/yaml
+  subpackages:
+  - .
+testImports:
+- name: github.com/stretchr/testify
+  version: 3333333333333333333333333333333333333333
+  subpackages:
+  - assert
+`)
+	defer cleanup()
+
+	depsFile, err := snapshot.ImportGlide(filename)
+	require.Nil(t, err)
+
+	assert.Equal(t, "{["+
+		"{github.com/desal/git  https://github.com/desal/git 1111111111111111111111111111111111111111  0001-01-01 00:00:00 +0000 UTC []  <nil> [] <nil>} "+
+		"{gopkg.in/yaml.v2  https://github.com/go-yaml/yaml 2222222222222222222222222222222222222222  0001-01-01 00:00:00 +0000 UTC []  <nil> [] <nil>}"+
+		"] ["+
+		"{github.com/stretchr/testify  https://github.com/stretchr/testify 3333333333333333333333333333333333333333  0001-01-01 00:00:00 +0000 UTC []  <nil> [] <nil>}"+
+		"] <nil>}", fmt.Sprintf("%v", depsFile))
+}
+
+func TestImportDep(t *testing.T) {
+	filename, cleanup := writeLockFile(t, "Gopkg.lock", `# This file is autogenerated, do not edit; changes may be undone by the next 'dep ensure'.
+
+[[projects]]
+  name = "github.com/stretchr/testify"
+  packages = ["assert","require"]
+  revision = "2222222222222222222222222222222222222222"
+  version = "v1.1.4"
+
+[[projects]]
+  branch = "master"
+  name = "github.com/desal/git"
+  packages = ["."]
+  revision = "1111111111111111111111111111111111111111"
+  source = "git@github.com:desal/git.git"
+
+[solve-meta]
+  analyzer-name = "dep"
+  analyzer-version = 1
+  inputs-digest = "0123456789abcdef"
+  solver-name = "gps-cdcl"
+  solver-version = 1
+`)
+	defer cleanup()
+
+	depsFile, err := snapshot.ImportDep(filename)
+	require.Nil(t, err)
+
+	assert.Equal(t, "{["+
+		"{github.com/desal/git  git@github.com:desal/git.git 1111111111111111111111111111111111111111 master 0001-01-01 00:00:00 +0000 UTC []  <nil> [] <nil>} "+
+		"{github.com/stretchr/testify  https://github.com/stretchr/testify 2222222222222222222222222222222222222222  0001-01-01 00:00:00 +0000 UTC [v1.1.4]  <nil> [] <nil>}"+
+		"] [] <nil>}", fmt.Sprintf("%v", depsFile))
+}
+
+func TestImportGovendor(t *testing.T) {
+	filename, cleanup := writeLockFile(t, "vendor.json", `{
+	"comment": "",
+	"ignore": "test",
+	"package": [
+		{
+			"checksumSHA1": "abc=",
+			"path": "github.com/desal/git",
+			"revision": "1111111111111111111111111111111111111111",
+			"revisionTime": "2017-03-04T05:16:17Z"
+		},
+		{
+			"checksumSHA1": "def=",
+			"path": "github.com/stretchr/testify/assert",
+			"revision": "2222222222222222222222222222222222222222",
+			"revisionTime": "2016-07-01T00:00:00Z",
+			"version": "v1.1",
+			"versionExact": "v1.1.4"
+		}
+	],
+	"rootPath": "example.com/mainpkg"
+}`)
+	defer cleanup()
+
+	depsFile, err := snapshot.ImportGovendor(filename)
+	require.Nil(t, err)
+
+	require.Equal(t, 2, len(depsFile.Deps))
+	assert.Equal(t, "github.com/desal/git", depsFile.Deps[0].ImportPath)
+	assert.Equal(t, time.Date(2017, 3, 4, 5, 16, 17, 0, time.UTC), depsFile.Deps[0].CommitTime)
+	assert.Equal(t, "github.com/stretchr/testify", depsFile.Deps[1].ImportPath)
+	assert.Equal(t, "https://github.com/stretchr/testify", depsFile.Deps[1].GitRemote)
+	assert.Equal(t, "2222222222222222222222222222222222222222", depsFile.Deps[1].SHA)
+	assert.Equal(t, []string{"v1.1.4"}, depsFile.Deps[1].Tags)
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	commitTime := time.Date(2017, 3, 4, 5, 16, 17, 0, time.UTC)
+	depsFile := snapshot.DepsFile{
+		Deps: []snapshot.PkgDep{
+			snapshot.PkgDep{ImportPath: "github.com/desal/git", GitRemote: "https://github.com/desal/git", SHA: "1111111111111111111111111111111111111111", CommitTime: commitTime, Tags: []string{"v1.0.0"}},
+			snapshot.PkgDep{ImportPath: "gopkg.in/yaml.v2", GitRemote: "https://github.com/go-yaml/yaml", SHA: "2222222222222222222222222222222222222222", CommitTime: commitTime},
+		},
+		TestDeps: []snapshot.PkgDep{
+			snapshot.PkgDep{ImportPath: "github.com/stretchr/testify", GitRemote: "https://github.com/stretchr/testify", SHA: "3333333333333333333333333333333333333333", CommitTime: commitTime},
+		},
+	}
+
+	for _, c := range []struct {
+		format        snapshot.LockFormat
+		filename      string
+		keepsTests    bool
+		keepsRemote   bool
+		keepsTags     bool
+		keepsTime     bool
+		expectedCount int
+	}{
+		{snapshot.LockFormat_Json, "snapshot.json", true, true, true, true, 2},
+		{snapshot.LockFormat_Godeps, "Godeps.json", false, false, true, false, 3},
+		{snapshot.LockFormat_Glide, "glide.lock", true, true, false, false, 2},
+		{snapshot.LockFormat_Dep, "Gopkg.lock", false, true, true, false, 3},
+		{snapshot.LockFormat_Govendor, "vendor.json", false, false, true, true, 3},
+	} {
+		filename, cleanup := writeLockFile(t, c.filename, "")
+
+		require.Nil(t, snapshot.Export(filename, depsFile, c.format), string(c.format))
+		format, err := snapshot.LockFormatOf(filename)
+		if c.format != snapshot.LockFormat_Json {
+			require.Nil(t, err)
+			assert.Equal(t, c.format, format)
+		}
+		imported, err := snapshot.Import(filename, c.format)
+		require.Nil(t, err, string(c.format))
+		cleanup()
+
+		lockFilename, err := snapshot.LockFilename(c.format)
+		require.Nil(t, err)
+		assert.Equal(t, c.filename, filepath.Base(lockFilename))
+
+		require.Equal(t, c.expectedCount, len(imported.Deps), string(c.format))
+		expected := map[string]snapshot.PkgDep{}
+		for _, pkgDep := range append(append([]snapshot.PkgDep{}, depsFile.Deps...), depsFile.TestDeps...) {
+			expected[pkgDep.ImportPath] = pkgDep
+		}
+
+		if c.keepsTests {
+			require.Equal(t, 1, len(imported.TestDeps), string(c.format))
+			assert.Equal(t, "github.com/stretchr/testify", imported.TestDeps[0].ImportPath)
+		}
+
+		for _, pkgDep := range append(append([]snapshot.PkgDep{}, imported.Deps...), imported.TestDeps...) {
+			expectedDep := expected[pkgDep.ImportPath]
+			assert.Equal(t, expectedDep.SHA, pkgDep.SHA, string(c.format))
+			if c.keepsRemote {
+				assert.Equal(t, expectedDep.GitRemote, pkgDep.GitRemote, string(c.format))
+			}
+			if c.keepsTags {
+				assert.Equal(t, len(expectedDep.Tags), len(pkgDep.Tags), string(c.format))
+			}
+			if c.keepsTime {
+				assert.True(t, expectedDep.CommitTime.Equal(pkgDep.CommitTime), string(c.format))
+			}
+		}
+	}
+}
+
+func TestLockFilename(t *testing.T) {
+	filename, err := snapshot.LockFilename(snapshot.LockFormat_Dep)
+	require.Nil(t, err)
+	assert.Equal(t, "Gopkg.lock", filename)
+
+	_, err = snapshot.LockFilename("glid")
+	assert.EqualError(t, err, "Unknown lock file format glid.")
+}
@@ -0,0 +1,159 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/desal/dsutil"
+	"github.com/desal/richtext"
+)
+
+//go:generate stringer -type LogDirection
+
+type LogDirection int
+
+const (
+	LogDirection_FastForward LogDirection = iota //To is a descendant of From
+	LogDirection_Rewind                          //To is an ancestor of From
+	LogDirection_Diverged                        //Each has commits the other doesn't
+)
+
+//Commit is a single commit in a CommitLog.
+type Commit struct {
+	SHA     string
+	Subject string
+}
+
+//CommitLog is the history between two revisions of a dependency. Added are
+//the commits reachable from To but not From, Removed the reverse, both
+//newest first. Error is set instead if the local checkout couldn't tell.
+type CommitLog struct {
+	From      string
+	To        string
+	Direction LogDirection
+	Added     []Commit `json:",omitempty"`
+	Removed   []Commit `json:",omitempty"`
+	Error     string   `json:",omitempty"`
+}
+
+var logDirectionLabels = map[LogDirection]string{
+	LogDirection_FastForward: "fast-forward",
+	LogDirection_Rewind:      "rewind",
+	LogDirection_Diverged:    "diverged",
+}
+
+func (a LogDirection) MarshalJSON() ([]byte, error) {
+	return json.Marshal(logDirectionLabels[a])
+}
+
+//goPathDir is the checkout of importPath in the first GOPATH entry that has
+//one.
+func (c *Context) goPathDir(importPath string) string {
+	for _, goPath := range c.goPath {
+		dir := filepath.Join(goPath, "src", filepath.FromSlash(importPath))
+		if dsutil.CheckPath(dir) {
+			return dir
+		}
+	}
+	return filepath.Join(c.goPath[0], "src", filepath.FromSlash(importPath))
+}
+
+func (c *Context) commits(dir, revisions string) ([]Commit, error) {
+	output, err := c.gitExecf(dir, "log --format='%%H %%s' %s", revisions)
+	if err != nil {
+		return nil, err
+	}
+
+	r := []Commit{}
+	for _, line := range strings.Split(output, "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.SplitN(line, " ", 2)
+		commit := Commit{SHA: fields[0]}
+		if len(fields) == 2 {
+			commit.Subject = fields[1]
+		}
+		r = append(r, commit)
+	}
+	return r, nil
+}
+
+//commitLog is the history of a git dependency from one SHA to another, read
+//from its checkout in GOPATH. It returns nil if either isn't a git SHA.
+func (c *Context) commitLog(from, to PkgDep) *CommitLog {
+	if from.SHA == "" || to.SHA == "" || from.SHA == to.SHA || from.VCS != "" || to.VCS != "" {
+		return nil
+	}
+
+	r := &CommitLog{From: from.SHA, To: to.SHA}
+	dir := c.goPathDir(to.ImportPath)
+	if !c.snapGitCtx.IsGit(dir) {
+		r.Error = fmt.Sprintf("No checkout of %s in GOPATH", to.ImportPath)
+		return r
+	}
+
+	for _, sha := range []string{from.SHA, to.SHA} {
+		if _, err := c.gitExecf(dir, "cat-file -e '%s^{commit}'", sha); err != nil {
+			r.Error = fmt.Sprintf("%s not found in %s", shortSHA(sha), dir)
+			return r
+		}
+	}
+
+	var err error
+	if r.Added, err = c.commits(dir, from.SHA+".."+to.SHA); err != nil {
+		r.Error = err.Error()
+		return r
+	} else if r.Removed, err = c.commits(dir, to.SHA+".."+from.SHA); err != nil {
+		r.Error = err.Error()
+		return r
+	}
+
+	if len(r.Removed) == 0 {
+		r.Direction = LogDirection_FastForward
+	} else if len(r.Added) == 0 {
+		r.Direction = LogDirection_Rewind
+	} else {
+		r.Direction = LogDirection_Diverged
+	}
+	return r
+}
+
+//AddLogs fills in the commit log of every dependency in diff that moved,
+//from the checkouts in GOPATH.
+func (c *Context) AddLogs(diff *DepsFileDiff) {
+	diffPkgs := []*DiffPkg{}
+	for _, list := range [][]DiffPkg{diff.Deps, diff.TestDeps} {
+		for i := range list {
+			if list[i].Old != nil && list[i].New != nil {
+				diffPkgs = append(diffPkgs, &list[i])
+			}
+		}
+	}
+
+	c.parallel(len(diffPkgs), func(i int) {
+		diffPkgs[i].Log = c.commitLog(*diffPkgs[i].Old, *diffPkgs[i].New)
+	}, nil)
+}
+
+//printCommitLog prints log beneath the line for its dependency, + for each
+//commit added and - for each removed.
+func printCommitLog(format richtext.Format, indent string, log *CommitLog) {
+	if log == nil {
+		return
+	}
+	if log.Error != "" {
+		format.PrintLine("%s(no log: %s)", indent, log.Error)
+		return
+	}
+
+	format.PrintLine("%s(%s)", indent, logDirectionLabels[log.Direction])
+	for _, commit := range log.Added {
+		format.PrintLine("%s+ %s %s", indent, shortSHA(commit.SHA), commit.Subject)
+	}
+	for _, commit := range log.Removed {
+		format.PrintLine("%s- %s %s", indent, shortSHA(commit.SHA), commit.Subject)
+	}
+}
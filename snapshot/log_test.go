@@ -0,0 +1,109 @@
+package snapshot_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/desal/go-snap/snapshot"
+	"github.com/desal/richtext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommitLog(t *testing.T) {
+	m := SetupRepos(t)
+	defer m.Close()
+
+	m.AddRepo("depone")
+	m.AddRepo("mainpkg")
+
+	sha := func() string {
+		out, _, _ := m.goCtx.Execf("cd src/depone; git rev-parse HEAD")
+		return strings.TrimSpace(out)
+	}
+
+	shaInit := sha()
+	m.goCtx.Execf(`
+		cd src/depone;
+		git checkout -q -b side;
+		echo 'package depone\n\nconst One = 0' > depone.go;
+		git add -A;
+		git commit -m "side"`)
+	shaSide := sha()
+	m.goCtx.Execf(`
+		cd src/depone;
+		git checkout -q master;
+		echo 'package depone\n\nconst One = 1' > depone.go;
+		git add -A;
+		git commit -m "one";
+		echo 'package depone\n\nconst One = 2' > depone.go;
+		git add -A;
+		git commit -m "two";
+		git push -q`)
+	shaTwo := sha()
+	shaOne, _, _ := m.goCtx.Execf("cd src/depone; git rev-parse HEAD~1")
+	shaOne = strings.TrimSpace(shaOne)
+
+	depsFile := func(sha string) snapshot.DepsFile {
+		return snapshot.DepsFile{Deps: []snapshot.PkgDep{{ImportPath: "depone", SHA: sha}}}
+	}
+
+	ctx := snapshot.New(richtext.Test(t), []string{m.gopath})
+	logOf := func(from, to string) *snapshot.CommitLog {
+		diff := snapshot.DiffDepsFiles(depsFile(from), depsFile(to))
+		ctx.AddLogs(&diff)
+		require.Equal(t, 1, len(diff.Deps))
+		return diff.Deps[0].Log
+	}
+
+	assert.Equal(t, &snapshot.CommitLog{
+		From:      shaInit,
+		To:        shaTwo,
+		Direction: snapshot.LogDirection_FastForward,
+		Added:     []snapshot.Commit{{shaTwo, "two"}, {shaOne, "one"}},
+		Removed:   []snapshot.Commit{},
+	}, logOf(shaInit, shaTwo))
+
+	assert.Equal(t, &snapshot.CommitLog{
+		From:      shaTwo,
+		To:        shaOne,
+		Direction: snapshot.LogDirection_Rewind,
+		Added:     []snapshot.Commit{},
+		Removed:   []snapshot.Commit{{shaTwo, "two"}},
+	}, logOf(shaTwo, shaOne))
+
+	diverged := logOf(shaOne, shaSide)
+	assert.Equal(t, snapshot.LogDirection_Diverged, diverged.Direction)
+	assert.Equal(t, []snapshot.Commit{{shaSide, "side"}}, diverged.Added)
+	assert.Equal(t, []snapshot.Commit{{shaOne, "one"}}, diverged.Removed)
+
+	jsonOutput, err := json.Marshal(diverged)
+	require.Nil(t, err)
+	assert.Contains(t, string(jsonOutput), `"Direction":"diverged"`)
+
+	missing := logOf(strings.Repeat("0", 40), shaTwo)
+	assert.Equal(t, "000000 not found in "+m.gopath+"/src/depone", missing.Error)
+
+	//Compare lists the commits since the pinned revision
+	m.goCtx.Execf(`
+		cd src/mainpkg;
+		echo 'package main\n\nimport "depone"\n\nvar _ = depone.One' > main.go;
+		git add -A;
+		git commit -m "gocode";
+		git push -q`)
+
+	buf := &bytes.Buffer{}
+	ctx = snapshot.New(richtext.Debug(buf), []string{m.gopath}, snapshot.Log)
+	result, ok := ctx.Compare(m.gopath, "mainpkg", []string{""}, depsFile(shaInit), true)
+	assert.False(t, ok)
+	require.NotNil(t, result[0].Log)
+	assert.Equal(t, snapshot.LogDirection_FastForward, result[0].Log.Direction)
+	assert.Equal(t, fmt.Sprintf(`[Red,None,[Bold]][FAIL][] depone (expected) %s vs (actual) %s
+              (fast-forward)
+              + %s two
+              + %s one
+`, shaInit[0:6], shaTwo[0:6], shaTwo[0:6], shaOne[0:6]), buf.String())
+}
@@ -0,0 +1,229 @@
+package snapshot
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/desal/cmd"
+	"github.com/desal/git"
+)
+
+//goModule is a single module from go list -m -json.
+type goModule struct {
+	Path    string
+	Version string
+	Main    bool
+	Dir     string
+	Time    *time.Time
+	Replace *goModule
+}
+
+//moduleList is every module in the build list of a module-aware project,
+//along with the hashes from its go.sum.
+type moduleList struct {
+	modules []goModule
+	sums    map[string]string
+}
+
+var pseudoVersionRe = regexp.MustCompile(`[-.]([0-9]{14})-([0-9a-f]{12})(\+incompatible)?$`)
+
+//goExecf runs a go command in dir for the module-aware operations gocmd
+//doesn't provide.
+func (c *Context) goExecf(dir, format string, a ...interface{}) (string, error) {
+	return c.goEnvExecf(dir, nil, format, a...)
+}
+
+//goEnvExecf is goExecf with environment variables set for the command, each
+//as NAME='value'.
+func (c *Context) goEnvExecf(dir string, env []string, format string, a ...interface{}) (string, error) {
+	stdout, stderr, err := cmd.New(dir, c.format).Execf(strings.Join(append(env, "go "+format), " "), a...)
+	if err != nil {
+		return "", fmt.Errorf("%s: %s", err.Error(), strings.TrimSpace(stderr))
+	}
+	return stdout, nil
+}
+
+//goList is the module-aware equivalent of gocmd's List, which can also
+//list for another platform, or in GOPATH mode, through env.
+func (c *Context) goList(workingDir string, env []string, tags, pkgString string) (map[string]map[string]interface{}, error) {
+	output, err := c.goEnvExecf(workingDir, env, "list -json -tags '%s' %s", tags, pkgString)
+	if err != nil {
+		return nil, err
+	}
+
+	r := map[string]map[string]interface{}{}
+	dec := json.NewDecoder(strings.NewReader(output))
+	for {
+		var e map[string]interface{}
+		if err := dec.Decode(&e); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		r[e["ImportPath"].(string)] = e
+	}
+	return r, nil
+}
+
+//listModules returns nil if workingDir isn't in a module-aware project.
+func (c *Context) listModules(workingDir string) (*moduleList, error) {
+	goMod, err := c.goExecf(workingDir, "env GOMOD")
+	if err != nil {
+		return nil, err
+	}
+	goMod = strings.TrimSpace(goMod)
+	if goMod == "" || goMod == os.DevNull {
+		return nil, nil
+	}
+
+	output, err := c.goExecf(workingDir, "list -m -json all")
+	if err != nil {
+		return nil, err
+	}
+
+	r := &moduleList{sums: map[string]string{}}
+	dec := json.NewDecoder(strings.NewReader(output))
+	for {
+		var module goModule
+		if err := dec.Decode(&module); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		r.modules = append(r.modules, module)
+	}
+
+	goSum, err := os.Open(filepath.Join(filepath.Dir(goMod), "go.sum"))
+	if os.IsNotExist(err) {
+		return r, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer goSum.Close()
+
+	scanner := bufio.NewScanner(goSum)
+	for scanner.Scan() {
+		//example.com/dep v1.2.3 h1:...
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 || strings.HasSuffix(fields[1], "/go.mod") {
+			continue
+		}
+		r.sums[fields[0]+" "+fields[1]] = fields[2]
+	}
+	return r, scanner.Err()
+}
+
+//owner returns the module providing importPath, nil for the standard library.
+func (l *moduleList) owner(importPath string) *goModule {
+	var r *goModule
+	for i, module := range l.modules {
+		if pkgContains(module.Path, importPath) && (r == nil || len(module.Path) > len(r.Path)) {
+			r = &l.modules[i]
+		}
+	}
+	return r
+}
+
+//moduleDep records a module dependency. Modules replaced with a local
+//directory are read from their repository like a GOPATH dependency, otherwise
+//the SHA and tags come from the version. notHead is whether that repository
+//is behind the head of its branch.
+func (c *Context) moduleDep(l *moduleList, module *goModule) (r *PkgDep, notHead bool) {
+	r = &PkgDep{
+		ImportPath: module.Path,
+		Module:     &Module{Path: module.Path, Version: module.Version},
+	}
+	source := module
+	if module.Replace != nil {
+		source = module.Replace
+		r.Module.Replace = source.Path
+		r.Module.Version = source.Version
+	}
+	r.Module.Sum = l.sums[source.Path+" "+source.Version]
+
+	if source.Version == "" {
+		vcs := c.snapVCS.detect(source.Dir)
+		if vcs == nil {
+			r.Error = c.errorf("Module %s (%s) is not a git, hg, bzr or svn repository", module.Path, source.Dir)
+			return r, false
+		}
+
+		r.Branch = c.branch(*r)
+		if r.Branch == "" {
+			r.Branch, _ = vcs.Branch(source.Dir)
+		}
+
+		status, _ := vcs.Status(source.Dir, r.Branch)
+		if status == git.NotMaster {
+			c.warnf("Module %s (%s) is not at the head of %s", module.Path, source.Dir, r.Branch)
+			notHead = true
+		} else if status != git.Clean {
+			err := StatusError{"Module", module.Path, source.Dir, status}
+			if c.dirtyAllowed(module.Path) {
+				c.warnf("%s, allowed", err.Error())
+			} else {
+				c.errorf("%s", err.Error())
+				r.Error = err
+			}
+		}
+
+		r.VCS = vcsName(vcs)
+		r.GitRemote, _ = vcs.RemoteUrl(source.Dir)
+		r.SHA, _ = vcs.Revision(source.Dir)
+		r.CommitTime, _ = vcs.CommitTime(source.Dir)
+		r.Tags, _ = vcs.Tags(source.Dir)
+		return r, notHead
+	}
+
+	if source.Time != nil {
+		r.CommitTime = *source.Time
+	}
+	if m := pseudoVersionRe.FindStringSubmatch(source.Version); m != nil {
+		r.SHA = m[2]
+	} else {
+		r.Tags = []string{strings.TrimSuffix(source.Version, "+incompatible")}
+	}
+	return r, false
+}
+
+//scanModules is the module-aware equivalent of scanDeps, recording the
+//module providing each dependency rather than its repository.
+func (c *Context) scanModules(l *moduleList, depSets ...stringSet) ([][]PkgDep, stringSet) {
+	doneModules := stringSet{}
+	r := [][]PkgDep{}
+	notHead := stringSet{}
+	for _, deps := range depSets {
+		pkgDeps := []PkgDep{}
+		for _, dep := range deps.Sorted() {
+			module := l.owner(dep)
+			if module == nil || module.Main {
+				continue
+			}
+			if _, done := doneModules[module.Path]; done {
+				continue
+			}
+			doneModules[module.Path] = empty{}
+			if c.ignored(module.Path) {
+				continue
+			}
+
+			pkgDep, behind := c.moduleDep(l, module)
+			if pkgDep.Error == nil {
+				c.verbosef("%s", module.Path)
+			}
+			if behind {
+				notHead[pkgDep.ImportPath] = empty{}
+			}
+			pkgDeps = append(pkgDeps, *pkgDep)
+		}
+		r = append(r, pkgDeps)
+	}
+	return r, notHead
+}
@@ -0,0 +1,226 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/desal/dsutil"
+	"github.com/desal/richtext"
+)
+
+//OutdatedPkg is how far a pinned dependency is behind the head of its
+//branch on the remote. AgeDays is how long ago the pinned commit was made.
+type OutdatedPkg struct {
+	ImportPath string
+	SHA        string
+	Branch     string
+	LatestSHA  string `json:",omitempty"`
+	Behind     int
+	NewestTag  string `json:",omitempty"`
+	CommitTime time.Time
+	AgeDays    int
+	Error      string `json:",omitempty"`
+}
+
+type OutdatedPkgs []OutdatedPkg
+
+func (a OutdatedPkgs) Len() int           { return len(a) }
+func (a OutdatedPkgs) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a OutdatedPkgs) Less(i, j int) bool { return a[i].ImportPath < a[j].ImportPath }
+
+//outdatedSource returns a git repository with the remote's branches and tags
+//for pkgDep and the prefix of its remote branches. That is the GOPATH
+//checkout after fetching, or as it is with the Offline flag, otherwise a
+//fresh bare clone that cleanup removes.
+func (c *Context) outdatedSource(pkgDep PkgDep) (string, string, func(), error) {
+	for _, goPath := range c.goPath {
+		dir := filepath.Join(goPath, "src", filepath.FromSlash(pkgDep.ImportPath))
+		if !dsutil.CheckPath(dir) || !c.reproduceGitCtx.IsGit(dir) {
+			continue
+		}
+		if !c.flags.Checked(Offline) {
+			if _, err := c.gitExecf(dir, "fetch --quiet --tags origin"); err != nil {
+				return "", "", nil, err
+			}
+		}
+		return dir, "origin/", func() {}, nil
+	}
+
+	if c.flags.Checked(Offline) {
+		return "", "", nil, fmt.Errorf("No checkout of %s in GOPATH.", pkgDep.ImportPath)
+	}
+
+	tempDir, err := ioutil.TempDir("", "go-snap-outdated")
+	if err != nil {
+		return "", "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(tempDir) }
+
+	if _, err := c.gitExecf(tempDir, "clone --quiet --bare '%s' .", c.remote(pkgDep)); err != nil {
+		cleanup()
+		return "", "", nil, err
+	}
+	return tempDir, "", cleanup, nil
+}
+
+//newestTag is the highest semver release in tags, or the highest pre-release
+//if there are no releases.
+func newestTag(tags []string) string {
+	r := ""
+	var newest semver
+	for _, tag := range tags {
+		v, ok := parseSemver(tag)
+		if !ok {
+			continue
+		}
+		switch {
+		case r == "":
+		case newest.pre != "" && v.pre == "":
+		case newest.pre == "" && v.pre != "":
+			continue
+		case v.compare(newest) <= 0:
+			continue
+		}
+		r, newest = tag, v
+	}
+	return r
+}
+
+func (c *Context) outdatedDep(pkgDep PkgDep) (OutdatedPkg, error) {
+	r := OutdatedPkg{ImportPath: pkgDep.ImportPath, SHA: pkgDep.SHA, Branch: c.branch(pkgDep), CommitTime: pkgDep.CommitTime}
+	if !pkgDep.CommitTime.IsZero() {
+		r.AgeDays = int(time.Since(pkgDep.CommitTime).Hours() / 24)
+	}
+
+	if pkgDep.VCS != "" {
+		return r, fmt.Errorf("%s is a %s repository, only git is supported.", pkgDep.ImportPath, pkgDep.VCS)
+	} else if pkgDep.SHA == "" {
+		return r, fmt.Errorf("No revision recorded for %s.", pkgDep.ImportPath)
+	}
+
+	dir, remotePrefix, cleanup, err := c.outdatedSource(pkgDep)
+	if err != nil {
+		return r, err
+	}
+	defer cleanup()
+
+	if r.Branch == "" && remotePrefix == "" {
+		//A bare clone's HEAD is the remote's default branch
+		r.Branch, err = c.gitExecf(dir, "symbolic-ref --short HEAD")
+	} else if r.Branch == "" {
+		r.Branch, err = c.reproduceVCS.named("").DefaultBranch(dir)
+	}
+	if err != nil {
+		return r, err
+	}
+
+	latest, err := c.gitExecf(dir, "rev-parse --verify -q '%s^{commit}'", remotePrefix+r.Branch)
+	if err != nil {
+		return r, fmt.Errorf("No branch %s on the remote of %s.", r.Branch, pkgDep.ImportPath)
+	}
+	r.LatestSHA = latest
+
+	if _, err := c.gitExecf(dir, "cat-file -e '%s^{commit}'", pkgDep.SHA); err != nil {
+		return r, fmt.Errorf("Pinned revision %s of %s not found.", shortSHA(pkgDep.SHA), pkgDep.ImportPath)
+	}
+	behind, err := c.gitExecf(dir, "rev-list --count %s..%s", pkgDep.SHA, r.LatestSHA)
+	if err != nil {
+		return r, err
+	}
+	if r.Behind, err = strconv.Atoi(behind); err != nil {
+		return r, err
+	}
+
+	revisions, err := c.reproduceVCS.named("").TagRevisions(dir)
+	if err != nil {
+		return r, err
+	}
+	tags := []string{}
+	for tag := range revisions {
+		tags = append(tags, tag)
+	}
+	r.NewestTag = newestTag(tags)
+	return r, nil
+}
+
+//Outdated reports how many commits each dependency in depsFile is behind the
+//head of its branch, the newest semver tag on its remote and how old the
+//pinned commit is. It returns false if any dependency couldn't be checked.
+func (c *Context) Outdated(depsFile DepsFile, doTests bool) ([]OutdatedPkg, bool) {
+	pkgDeps := append([]PkgDep{}, depsFile.Deps...)
+	if doTests {
+		pkgDeps = append(pkgDeps, depsFile.TestDeps...)
+	}
+
+	result := make([]OutdatedPkg, len(pkgDeps))
+	c.parallel(len(pkgDeps), func(i int) {
+		outdatedPkg, err := c.outdatedDep(pkgDeps[i])
+		if err != nil {
+			outdatedPkg.Error = err.Error()
+		}
+		result[i] = outdatedPkg
+	}, nil)
+
+	sort.Sort(OutdatedPkgs(result))
+
+	ok := true
+	for _, outdatedPkg := range result {
+		if outdatedPkg.Error != "" {
+			ok = false
+		}
+	}
+	return result, ok
+}
+
+func (c *Context) PrintOutdated(result []OutdatedPkg) {
+	maxLen := 0
+	for _, outdatedPkg := range result {
+		if len(outdatedPkg.ImportPath) > maxLen {
+			maxLen = len(outdatedPkg.ImportPath)
+		}
+	}
+
+	padding := strings.Repeat(" ", maxLen)
+
+	green := c.format.MakePrintf(richtext.Green, richtext.None, richtext.Bold)
+	orange := c.format.MakePrintf(richtext.Orange, richtext.None, richtext.Bold)
+	red := c.format.MakePrintf(richtext.Red, richtext.None, richtext.Bold)
+
+	for _, outdatedPkg := range result {
+		var message string
+		if outdatedPkg.Error != "" {
+			red("[FAIL]")
+			message = outdatedPkg.Error
+		} else {
+			if outdatedPkg.Behind == 0 {
+				green("[ OK ]")
+			} else {
+				orange("[ OLD]")
+			}
+			message = fmt.Sprintf("%s %4d behind %s", shortSHA(outdatedPkg.SHA), outdatedPkg.Behind, outdatedPkg.Branch)
+			if outdatedPkg.NewestTag != "" {
+				message += ", newest tag " + outdatedPkg.NewestTag
+			}
+			if !outdatedPkg.CommitTime.IsZero() {
+				message += fmt.Sprintf(", %d days old", outdatedPkg.AgeDays)
+			}
+		}
+		c.format.PrintLine(" %s %s", (outdatedPkg.ImportPath + padding)[0:maxLen], message)
+	}
+}
+
+func WriteOutdatedJson(filename string, result []OutdatedPkg) error {
+	jsonOutput, err := json.MarshalIndent(&result, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeOutput(filename, jsonOutput)
+}
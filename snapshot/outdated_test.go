@@ -0,0 +1,76 @@
+package snapshot_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/desal/dsutil"
+	"github.com/desal/git"
+	"github.com/desal/go-snap/snapshot"
+	"github.com/desal/richtext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutdated(t *testing.T) {
+	m := SetupRepos(t)
+	defer m.Close()
+
+	m.AddRepo("depone")
+	m.AddRepo("deptwo")
+
+	gitCtx := git.New(richtext.Test(t), git.MustPanic)
+	pinnedSHA, _ := gitCtx.SHA(m.gopath + "/src/depone")
+	m.goCtx.Execf(`
+		cd src/depone;
+		echo 'package depone\n\nconst One = 1' > depone.go;
+		git add -A;
+		git commit -m "one";
+		git tag -a -m "v1.0.0" v1.0.0;
+		echo 'package depone\n\nconst One = 2' > depone.go;
+		git add -A;
+		git commit -m "two";
+		git tag -a -m "v1.1.0-rc.1" v1.1.0-rc.1;
+		git push -q --tags origin master`)
+	latestSHA, _ := gitCtx.SHA(m.bareDir + "/depone")
+	deptwoSHA, _ := gitCtx.SHA(m.gopath + "/src/deptwo")
+
+	depsFile := snapshot.DepsFile{
+		Deps: []snapshot.PkgDep{
+			{ImportPath: "depone", GitRemote: dsutil.PosixPath(m.bareDir) + "/depone", SHA: pinnedSHA, CommitTime: time.Now().Add(-73 * time.Hour)},
+		},
+		TestDeps: []snapshot.PkgDep{
+			{ImportPath: "deptwo", GitRemote: dsutil.PosixPath(m.bareDir) + "/deptwo", SHA: deptwoSHA},
+		},
+	}
+
+	expected := []snapshot.OutdatedPkg{
+		{ImportPath: "depone", SHA: pinnedSHA, Branch: "master", LatestSHA: latestSHA, Behind: 2, NewestTag: "v1.0.0", CommitTime: depsFile.Deps[0].CommitTime, AgeDays: 3},
+		{ImportPath: "deptwo", SHA: deptwoSHA, Branch: "master", LatestSHA: deptwoSHA},
+	}
+
+	//From the GOPATH checkouts
+	ctx := snapshot.New(richtext.Test(t), []string{m.gopath}, snapshot.Offline)
+	result, ok := ctx.Outdated(depsFile, true)
+	assert.True(t, ok)
+	assert.Equal(t, expected, result)
+	ctx.PrintOutdated(result)
+
+	result, ok = ctx.Outdated(depsFile, false)
+	assert.True(t, ok)
+	assert.Equal(t, expected[:1], result)
+
+	//From fresh clones of the remotes
+	require.Nil(t, os.RemoveAll(m.gopath+"/src/depone"))
+	ctx = snapshot.New(richtext.Test(t), []string{m.gopath})
+	result, ok = ctx.Outdated(depsFile, true)
+	assert.True(t, ok)
+	assert.Equal(t, expected, result)
+
+	ctx = snapshot.New(richtext.Test(t), []string{m.gopath}, snapshot.Offline)
+	result, ok = ctx.Outdated(depsFile, true)
+	assert.False(t, ok)
+	assert.Equal(t, "No checkout of depone in GOPATH.", result[0].Error)
+	assert.Equal(t, "", result[1].Error)
+}
@@ -0,0 +1,181 @@
+package snapshot
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+type (
+	//Platforms are GOOS/GOARCH pairs, such as linux/amd64, Snapshot lists
+	//packages for in addition to each tag set. Without any only the host
+	//platform is listed. As an Option it replaces any already given.
+	Platforms []string
+
+	//Build is a configuration packages were listed under by Snapshot, a tag
+	//set and a platform, blank for the host.
+	Build struct {
+		Tags     string `json:",omitempty"`
+		Platform string `json:",omitempty"`
+	}
+)
+
+//ParsePlatforms builds Platforms from comma separated lists of GOOS/GOARCH
+//pairs, as given on the command line.
+func ParsePlatforms(lists []string) (Platforms, error) {
+	r := Platforms{}
+	for _, list := range lists {
+		for _, platform := range strings.Split(list, ",") {
+			platform = strings.TrimSpace(platform)
+			parts := strings.Split(platform, "/")
+			if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+				return nil, fmt.Errorf("Invalid platform '%s', expected GOOS/GOARCH such as linux/amd64.", platform)
+			}
+			r = append(r, platform)
+		}
+	}
+	return r, nil
+}
+
+func (p Platforms) apply(c *Context) {
+	c.platforms = p
+}
+
+func (p *Platforms) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	lists := []string{}
+	if err := unmarshal(&lists); err != nil {
+		return err
+	}
+
+	platforms, err := ParsePlatforms(lists)
+	if err != nil {
+		return err
+	}
+	*p = platforms
+	return nil
+}
+
+//builds is every combination of tagSets and the Context's platforms, in the
+//order they are listed.
+func (c *Context) builds(tagSets []string) []Build {
+	platforms := c.platforms
+	if len(platforms) == 0 {
+		platforms = Platforms{""}
+	}
+
+	r := []Build{}
+	for _, tags := range tagSets {
+		for _, platform := range platforms {
+			r = append(r, Build{Tags: tags, Platform: platform})
+		}
+	}
+	return r
+}
+
+//env is the environment go list is run with for b, as NAME='value'.
+func (b Build) env() []string {
+	if b.Platform == "" {
+		return nil
+	}
+	parts := strings.SplitN(b.Platform, "/", 2)
+	return []string{fmt.Sprintf("GOOS='%s'", parts[0]), fmt.Sprintf("GOARCH='%s'", parts[1])}
+}
+
+//goPathEnv is the environment to run go commands in GOPATH mode with.
+func (c *Context) goPathEnv() []string {
+	return []string{
+		fmt.Sprintf("GOPATH='%s'", strings.Join(c.goPath, string(filepath.ListSeparator))),
+		"GO111MODULE='off'",
+	}
+}
+
+//addBuilds records against each of pkgDeps the builds whose dependencies,
+//buildDeps, include one of its packages. Nothing is recorded if only the
+//default build was listed.
+func addBuilds(pkgDeps []PkgDep, builds []Build, buildDeps []stringSet) {
+	if len(builds) == 1 && builds[0] == (Build{}) {
+		return
+	}
+
+	for i := range pkgDeps {
+		for j, build := range builds {
+			for dep := range buildDeps[j] {
+				if pkgContains(pkgDeps[i].ImportPath, dep) {
+					pkgDeps[i].Builds = append(pkgDeps[i].Builds, build)
+					break
+				}
+			}
+		}
+	}
+}
+
+//SelectBuilds returns the dependencies in depsFile required by any build
+//with one of tagSets and one of platforms, either of which may be empty to
+//allow any. Dependencies without Builds recorded are always required. It is
+//an error to select a tag set or platform no Build was recorded with, so a
+//snapshot of the host platform cannot be selected by platform.
+func SelectBuilds(depsFile DepsFile, tagSets []string, platforms Platforms) (DepsFile, error) {
+	snapshotted := map[Build]empty{}
+	for _, pkgDeps := range [][]PkgDep{depsFile.Deps, depsFile.TestDeps} {
+		for _, pkgDep := range pkgDeps {
+			for _, build := range pkgDep.Builds {
+				snapshotted[build] = empty{}
+			}
+		}
+	}
+	if len(snapshotted) == 0 {
+		snapshotted[Build{}] = empty{}
+	}
+
+	tagSetSet, platformSet := stringSet{}, stringSet{}
+	for _, tags := range tagSets {
+		tagSetSet[tags] = empty{}
+	}
+	for _, platform := range platforms {
+		platformSet[platform] = empty{}
+	}
+
+	selected := func(build Build) bool {
+		if _, ok := tagSetSet[build.Tags]; !ok && len(tagSets) != 0 {
+			return false
+		}
+		if _, ok := platformSet[build.Platform]; !ok && len(platforms) != 0 {
+			return false
+		}
+		return true
+	}
+
+	for _, tags := range tagSets {
+		found := false
+		for build := range snapshotted {
+			found = found || build.Tags == tags
+		}
+		if !found {
+			return DepsFile{}, fmt.Errorf("Tag set '%s' was not snapshotted.", tags)
+		}
+	}
+	for _, platform := range platforms {
+		found := false
+		for build := range snapshotted {
+			found = found || build.Platform == platform
+		}
+		if !found {
+			return DepsFile{}, fmt.Errorf("Platform '%s' was not snapshotted.", platform)
+		}
+	}
+
+	selectPkgDeps := func(pkgDeps []PkgDep) []PkgDep {
+		r := []PkgDep{}
+		for _, pkgDep := range pkgDeps {
+			required := len(pkgDep.Builds) == 0
+			for _, build := range pkgDep.Builds {
+				required = required || selected(build)
+			}
+			if required {
+				r = append(r, pkgDep)
+			}
+		}
+		return r
+	}
+	return DepsFile{Deps: selectPkgDeps(depsFile.Deps), TestDeps: selectPkgDeps(depsFile.TestDeps)}, nil
+}
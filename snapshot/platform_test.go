@@ -0,0 +1,131 @@
+package snapshot_test
+
+import (
+	"testing"
+
+	"github.com/desal/go-snap/snapshot"
+	"github.com/desal/richtext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePlatforms(t *testing.T) {
+	platforms, err := snapshot.ParsePlatforms([]string{"linux/amd64, windows/amd64", "darwin/arm64"})
+	require.Nil(t, err)
+	assert.Equal(t, snapshot.Platforms{"linux/amd64", "windows/amd64", "darwin/arm64"}, platforms)
+
+	_, err = snapshot.ParsePlatforms([]string{"linux/amd64,windows"})
+	assert.EqualError(t, err, "Invalid platform 'windows', expected GOOS/GOARCH such as linux/amd64.")
+}
+
+func TestSnapshotPlatforms(t *testing.T) {
+	m := SetupRepos(t)
+	defer m.Close()
+
+	m.AddRepo("depone")
+	m.AddRepo("depwin")
+	m.AddRepo("deptag")
+	m.AddRepo("mainpkg")
+
+	//Only depone has a file go list finds for the host without tags
+	constraints := map[string]string{"depone": "", "depwin": "// +build windows\n\n", "deptag": "// +build extra\n\n"}
+	for _, dep := range []string{"depone", "depwin", "deptag"} {
+		m.goCtx.Execf(`
+			cd src/%s;
+			echo '%spackage %s\n\nconst N = 1' > %s.go;
+			git add -A;
+			git commit -m "gocode";
+			git push`, dep, constraints[dep], dep, dep)
+	}
+	m.goCtx.Execf(`
+		cd src/mainpkg;
+		echo 'package main\n\nimport "depone"\n\nvar _ = depone.N' > main.go;
+		echo 'package main\n\nimport "depwin"\n\nvar _ = depwin.N' > main_windows.go;
+		echo '// +build extra\n\npackage main\n\nimport "deptag"\n\nvar _ = deptag.N' > main_extra.go;
+		git add -A;
+		git commit -m "gocode";
+		git push`)
+
+	ctx := snapshot.New(richtext.Test(t), []string{m.gopath})
+	depsFile, err := ctx.Snapshot(m.gopath, "mainpkg", []string{""})
+	require.Nil(t, err)
+	require.Equal(t, 1, len(depsFile.Deps))
+	assert.Nil(t, depsFile.Deps[0].Builds)
+
+	ctx = snapshot.New(richtext.Test(t), []string{m.gopath}, snapshot.Platforms{"linux/amd64", "windows/amd64"})
+	depsFile, err = ctx.Snapshot(m.gopath, "mainpkg", []string{"", "extra"})
+	require.Nil(t, err)
+	require.Equal(t, 3, len(depsFile.Deps))
+
+	linux := snapshot.Build{Platform: "linux/amd64"}
+	windows := snapshot.Build{Platform: "windows/amd64"}
+	linuxExtra := snapshot.Build{Tags: "extra", Platform: "linux/amd64"}
+	windowsExtra := snapshot.Build{Tags: "extra", Platform: "windows/amd64"}
+	assert.Equal(t, "depone", depsFile.Deps[0].ImportPath)
+	assert.Equal(t, []snapshot.Build{linux, windows, linuxExtra, windowsExtra}, depsFile.Deps[0].Builds)
+	assert.Equal(t, "deptag", depsFile.Deps[1].ImportPath)
+	assert.Equal(t, []snapshot.Build{linuxExtra, windowsExtra}, depsFile.Deps[1].Builds)
+	assert.Equal(t, "depwin", depsFile.Deps[2].ImportPath)
+	assert.Equal(t, []snapshot.Build{windows, windowsExtra}, depsFile.Deps[2].Builds)
+}
+
+func TestSelectBuilds(t *testing.T) {
+	linux := snapshot.Build{Platform: "linux/amd64"}
+	windows := snapshot.Build{Platform: "windows/amd64"}
+	linuxExtra := snapshot.Build{Tags: "extra", Platform: "linux/amd64"}
+	depsFile := snapshot.DepsFile{
+		Deps: []snapshot.PkgDep{
+			{ImportPath: "depall", Builds: []snapshot.Build{linux, windows, linuxExtra}},
+			{ImportPath: "depextra", Builds: []snapshot.Build{linuxExtra}},
+			{ImportPath: "depunknown"},
+			{ImportPath: "depwin", Builds: []snapshot.Build{windows}},
+		},
+		TestDeps: []snapshot.PkgDep{
+			{ImportPath: "deptest", Builds: []snapshot.Build{linuxExtra}},
+		},
+	}
+
+	importPaths := func(pkgDeps []snapshot.PkgDep) []string {
+		r := []string{}
+		for _, pkgDep := range pkgDeps {
+			r = append(r, pkgDep.ImportPath)
+		}
+		return r
+	}
+
+	selected, err := snapshot.SelectBuilds(depsFile, nil, nil)
+	require.Nil(t, err)
+	assert.Equal(t, depsFile, selected)
+
+	selected, err = snapshot.SelectBuilds(depsFile, []string{""}, nil)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"depall", "depunknown", "depwin"}, importPaths(selected.Deps))
+	assert.Equal(t, []string{}, importPaths(selected.TestDeps))
+
+	selected, err = snapshot.SelectBuilds(depsFile, []string{"extra"}, snapshot.Platforms{"linux/amd64"})
+	require.Nil(t, err)
+	assert.Equal(t, []string{"depall", "depextra", "depunknown"}, importPaths(selected.Deps))
+	assert.Equal(t, []string{"deptest"}, importPaths(selected.TestDeps))
+
+	selected, err = snapshot.SelectBuilds(depsFile, nil, snapshot.Platforms{"windows/amd64"})
+	require.Nil(t, err)
+	assert.Equal(t, []string{"depall", "depunknown", "depwin"}, importPaths(selected.Deps))
+
+	_, err = snapshot.SelectBuilds(depsFile, []string{"missing"}, nil)
+	assert.EqualError(t, err, "Tag set 'missing' was not snapshotted.")
+
+	_, err = snapshot.SelectBuilds(depsFile, nil, snapshot.Platforms{"darwin/arm64"})
+	assert.EqualError(t, err, "Platform 'darwin/arm64' was not snapshotted.")
+
+	//Recorded for the host platform, whatever that was, so no platform can
+	//be selected
+	hostFile := snapshot.DepsFile{Deps: []snapshot.PkgDep{
+		{ImportPath: "depone", Builds: []snapshot.Build{{}}},
+		{ImportPath: "deptag", Builds: []snapshot.Build{{Tags: "extra"}}},
+	}}
+	_, err = snapshot.SelectBuilds(hostFile, []string{""}, snapshot.Platforms{"darwin/arm64"})
+	assert.EqualError(t, err, "Platform 'darwin/arm64' was not snapshotted.")
+	selected, err = snapshot.SelectBuilds(hostFile, []string{""}, nil)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"depone"}, importPaths(selected.Deps))
+}
@@ -0,0 +1,147 @@
+package snapshot
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+//Policy sets the severity differences are reported with by Compare, in place
+//of the defaults. It is keyed by category, as in a policy file:
+//  newer: ok
+//  removed: fail
+//  test-only: warn
+//test-only, if set, applies to every difference in a dependency used
+//exclusively for tests, except one that is dirty or failed to scan, which is
+//a problem with the checkout rather than the snapshot. Policy is an Option,
+//adding to any already given.
+type Policy map[string]CompareResult
+
+//PolicyTestOnly is the Policy key for differences in test dependencies.
+const PolicyTestOnly = "test-only"
+
+var severityNames = map[string]CompareResult{
+	"ok":    CompareResult_Ok,
+	"warn":  CompareResult_Warn,
+	"fail":  CompareResult_Error,
+	"error": CompareResult_Error,
+}
+
+func (p Policy) apply(c *Context) {
+	c.policy = c.policy.Merge(p)
+}
+
+//ParsePolicy builds a Policy from category and severity (ok, warn or fail)
+//names.
+func ParsePolicy(settings map[string]string) (Policy, error) {
+	categories := map[string]bool{PolicyTestOnly: true}
+	for category, label := range compareCategoryLabels {
+		categories[label] = category != CompareCategory_Match
+	}
+
+	r := Policy{}
+	for key, value := range settings {
+		if !categories[key] {
+			return nil, fmt.Errorf("Unknown comparison category '%s', expected one of %s.", key, strings.Join(policyKeys(), ", "))
+		}
+		severity, ok := severityNames[strings.ToLower(value)]
+		if !ok {
+			return nil, fmt.Errorf("Unknown severity '%s' for %s, expected ok, warn or fail.", value, key)
+		}
+		r[key] = severity
+	}
+	return r, nil
+}
+
+func policyKeys() []string {
+	r := []string{PolicyTestOnly}
+	for category, label := range compareCategoryLabels {
+		if category != CompareCategory_Match {
+			r = append(r, label)
+		}
+	}
+	sort.Strings(r)
+	return r
+}
+
+//ParsePolicySettings builds a Policy from category=severity settings, as
+//given on the command line.
+func ParsePolicySettings(settings []string) (Policy, error) {
+	m := map[string]string{}
+	for _, setting := range settings {
+		parts := strings.SplitN(setting, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("Invalid severity '%s', expected category=severity.", setting)
+		}
+		m[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return ParsePolicy(m)
+}
+
+func (p *Policy) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	settings := map[string]string{}
+	if err := unmarshal(&settings); err != nil {
+		return err
+	}
+
+	policy, err := ParsePolicy(settings)
+	if err != nil {
+		return err
+	}
+	*p = policy
+	return nil
+}
+
+//ReadPolicy reads a policy file.
+func ReadPolicy(filename string) (Policy, error) {
+	var policy Policy
+
+	input, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	err = yaml.UnmarshalStrict(input, &policy)
+	return policy, err
+}
+
+//Merge returns p with the settings in other taking precedence.
+func (p Policy) Merge(other Policy) Policy {
+	r := Policy{}
+	for _, policy := range []Policy{p, other} {
+		for key, severity := range policy {
+			r[key] = severity
+		}
+	}
+	return r
+}
+
+//severity is how comparePkg should be reported under p.
+func (p Policy) severity(comparePkg ComparePkg) CompareResult {
+	if comparePkg.Category == CompareCategory_Match {
+		return CompareResult_Ok
+	}
+	if severity, ok := p[PolicyTestOnly]; ok && comparePkg.TestDep &&
+		comparePkg.Category != CompareCategory_Dirty && comparePkg.Category != CompareCategory_Failed {
+		return severity
+	}
+	if severity, ok := p[compareCategoryLabels[comparePkg.Category]]; ok {
+		return severity
+	}
+	return defaultSeverities[comparePkg.Category]
+}
+
+//WithPolicy returns the report with each severity set by policy.
+func (r CompareReport) WithPolicy(policy Policy) CompareReport {
+	apply := func(comparePkgs []ComparePkg) []ComparePkg {
+		result := append([]ComparePkg{}, comparePkgs...)
+		for i := range result {
+			result[i].CompareResult = policy.severity(result[i])
+		}
+		return result
+	}
+	return CompareReport{Deps: apply(r.Deps), TestDeps: apply(r.TestDeps)}
+}
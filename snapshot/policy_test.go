@@ -0,0 +1,140 @@
+package snapshot_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/desal/go-snap/snapshot"
+	"github.com/desal/richtext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy(t *testing.T) {
+	policy, err := snapshot.ParsePolicy(map[string]string{"newer": "ok", "removed": "FAIL", "test-only": "warn"})
+	require.Nil(t, err)
+	assert.Equal(t, snapshot.Policy{"newer": snapshot.CompareResult_Ok, "removed": snapshot.CompareResult_Error, "test-only": snapshot.CompareResult_Warn}, policy)
+
+	_, err = snapshot.ParsePolicy(map[string]string{"match": "fail"})
+	assert.EqualError(t, err, "Unknown comparison category 'match', expected one of dirty, failed, moved, new, newer, not-on-master, older, removed, retagged, test-only.")
+	_, err = snapshot.ParsePolicy(map[string]string{"newer": "maybe"})
+	assert.EqualError(t, err, "Unknown severity 'maybe' for newer, expected ok, warn or fail.")
+
+	flagPolicy, err := snapshot.ParsePolicySettings([]string{"newer=warn", " older = ok "})
+	require.Nil(t, err)
+	assert.Equal(t, snapshot.Policy{"newer": snapshot.CompareResult_Warn, "older": snapshot.CompareResult_Ok}, flagPolicy)
+	_, err = snapshot.ParsePolicySettings([]string{"newer"})
+	assert.EqualError(t, err, "Invalid severity 'newer', expected category=severity.")
+
+	assert.Equal(t, snapshot.Policy{
+		"newer":     snapshot.CompareResult_Warn,
+		"older":     snapshot.CompareResult_Ok,
+		"removed":   snapshot.CompareResult_Error,
+		"test-only": snapshot.CompareResult_Warn,
+	}, policy.Merge(flagPolicy))
+
+	dir, err := ioutil.TempDir("", "snapshot_test_policy")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "policy.yml")
+	require.Nil(t, ioutil.WriteFile(filename, []byte("newer: ok\nremoved: fail\ntest-only: warn\n"), 0644))
+	filePolicy, err := snapshot.ReadPolicy(filename)
+	require.Nil(t, err)
+	assert.Equal(t, policy, filePolicy)
+
+	require.Nil(t, ioutil.WriteFile(filename, []byte("newest: ok\n"), 0644))
+	_, err = snapshot.ReadPolicy(filename)
+	assert.NotNil(t, err)
+
+	day := func(d int) time.Time { return time.Date(2017, 1, d, 0, 0, 0, 0, time.UTC) }
+	expected := snapshot.DepsFile{
+		Deps: []snapshot.PkgDep{
+			{ImportPath: "newer", SHA: "aaa", CommitTime: day(1)},
+			{ImportPath: "removed", SHA: "bbb"},
+		},
+		TestDeps: []snapshot.PkgDep{
+			{ImportPath: "testdep", SHA: "ccc", CommitTime: day(2)},
+		},
+	}
+	actual := snapshot.DepsFile{
+		Deps: []snapshot.PkgDep{
+			{ImportPath: "newer", SHA: "111", CommitTime: day(2)},
+		},
+		TestDeps: []snapshot.PkgDep{
+			{ImportPath: "testdep", SHA: "222", CommitTime: day(1)},
+		},
+	}
+
+	severities := func(report snapshot.CompareReport) []snapshot.CompareResult {
+		r := []snapshot.CompareResult{}
+		for _, comparePkg := range report.All() {
+			r = append(r, comparePkg.CompareResult)
+		}
+		return r
+	}
+
+	report := snapshot.CompareDepsFiles(expected, actual, true)
+	assert.Equal(t, []snapshot.CompareResult{snapshot.CompareResult_Error, snapshot.CompareResult_Warn, snapshot.CompareResult_Error}, severities(report))
+	assert.False(t, report.Ok())
+
+	//A dev branch tolerating newer dependencies
+	report = report.WithPolicy(policy)
+	assert.Equal(t, []snapshot.CompareResult{snapshot.CompareResult_Ok, snapshot.CompareResult_Error, snapshot.CompareResult_Warn}, severities(report))
+
+	report = report.WithPolicy(snapshot.Policy{"newer": snapshot.CompareResult_Ok, "older": snapshot.CompareResult_Ok, "removed": snapshot.CompareResult_Ok})
+	assert.True(t, report.Ok())
+
+	//test-only does not cover a test dependency that could not be scanned
+	failed := snapshot.DepsFile{TestDeps: []snapshot.PkgDep{{ImportPath: "testdep", Error: errors.New("failed")}}}
+	report = snapshot.CompareDepsFiles(expected, failed, true).WithPolicy(policy)
+	assert.Equal(t, snapshot.CompareCategory_Failed, report.TestDeps[0].Category)
+	assert.Equal(t, snapshot.CompareResult_Error, report.TestDeps[0].CompareResult)
+}
+
+func TestPolicyNotOnMaster(t *testing.T) {
+	m := SetupRepos(t)
+	defer m.Close()
+
+	m.AddRepo("depone")
+	m.AddRepo("mainpkg")
+
+	m.goCtx.Execf(`
+		cd src/depone;
+		echo 'package depone\n\nconst One = 1' > depone.go;
+		git add -A;
+		git commit -m "one"`)
+	pinnedSHA, _, _ := m.goCtx.Execf("cd src/depone; git rev-parse HEAD")
+	pinnedSHA = strings.TrimSpace(pinnedSHA)
+	m.goCtx.Execf(`
+		cd src/depone;
+		echo 'package depone\n\nconst One = 2' > depone.go;
+		git add -A;
+		git commit -m "two";
+		git push -q;
+		git checkout -q %s`, pinnedSHA)
+	m.goCtx.Execf(`
+		cd src/mainpkg;
+		echo 'package main\n\nimport "depone"\n\nvar _ = depone.One' > main.go;
+		git add -A;
+		git commit -m "gocode";
+		git push -q`)
+
+	depsFile := snapshot.DepsFile{Deps: []snapshot.PkgDep{{ImportPath: "depone", SHA: pinnedSHA}}}
+
+	ctx := snapshot.New(richtext.Test(t), []string{m.gopath})
+	report := ctx.Report(m.gopath, "mainpkg", []string{""}, depsFile, true)
+	require.Equal(t, 1, len(report.Deps))
+	assert.Equal(t, snapshot.CompareCategory_NotOnMaster, report.Deps[0].Category)
+	assert.Equal(t, "Not at the head of master", report.Deps[0].Message)
+	assert.True(t, report.Ok())
+
+	ctx = snapshot.New(richtext.Test(t), []string{m.gopath}, snapshot.Policy{"not-on-master": snapshot.CompareResult_Error})
+	report = ctx.Report(m.gopath, "mainpkg", []string{""}, depsFile, true)
+	assert.False(t, report.Ok())
+}
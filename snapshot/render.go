@@ -0,0 +1,71 @@
+package snapshot
+
+import (
+	"strings"
+
+	"github.com/desal/richtext"
+)
+
+//Renderer presents a CompareReport, for example to a terminal or a file.
+type Renderer interface {
+	Render(report CompareReport) error
+}
+
+type (
+	tableRenderer struct {
+		format richtext.Format
+	}
+
+	writeRenderer struct {
+		filename string
+		output   CompareOutput
+	}
+)
+
+//NewTableRenderer renders a report as colored lines, one per dependency.
+//It is what Compare uses.
+func NewTableRenderer(format richtext.Format) Renderer {
+	return tableRenderer{format}
+}
+
+//NewWriteRenderer renders a report to filename, or stdout, in a
+//machine-readable format. See WriteCompare.
+func NewWriteRenderer(filename string, output CompareOutput) Renderer {
+	return writeRenderer{filename, output}
+}
+
+func (r tableRenderer) Render(report CompareReport) error {
+	printComparePkgs(r.format, report.All())
+	return nil
+}
+
+func (r writeRenderer) Render(report CompareReport) error {
+	return WriteCompare(r.filename, report.All(), r.output)
+}
+
+func printComparePkgs(format richtext.Format, result []ComparePkg) {
+	maxLen := 0
+	for _, comparePkg := range result {
+		if len(comparePkg.ImportPath) > maxLen {
+			maxLen = len(comparePkg.ImportPath)
+		}
+	}
+
+	padding := strings.Repeat(" ", maxLen)
+
+	green := format.MakePrintf(richtext.Green, richtext.None, richtext.Bold)
+	orange := format.MakePrintf(richtext.Orange, richtext.None, richtext.Bold)
+	red := format.MakePrintf(richtext.Red, richtext.None, richtext.Bold)
+
+	richPrefix := map[CompareResult]func(){
+		CompareResult_Ok:    func() { green("[ OK ]") },
+		CompareResult_Warn:  func() { orange("[WARN]") },
+		CompareResult_Error: func() { red("[FAIL]") },
+	}
+
+	for _, comparePkg := range result {
+		richPrefix[comparePkg.CompareResult]()
+		format.PrintLine(" %s %s", (comparePkg.ImportPath + padding)[0:maxLen], comparePkg.Message)
+		printCommitLog(format, strings.Repeat(" ", 8+maxLen), comparePkg.Log)
+	}
+}
@@ -1,10 +1,17 @@
 package snapshot
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/desal/dsutil"
 	"github.com/desal/git"
+	"github.com/desal/richtext"
 )
 
 type AlreadyExists int
@@ -17,79 +24,638 @@ const (
 	AlreadyExists_UpdateLatest
 )
 
-func (c *Context) reproduceDep(pkgDep PkgDep, alreadyExists AlreadyExists) error {
-	dir := filepath.Join(c.goPath[0], "src", pkgDep.ImportPath)
-	var sha string
-	var err error
+//go:generate stringer -type Action
+
+type Action int
+
+const (
+	Action_Skip Action = iota
+	Action_Clone
+	Action_Checkout
+	Action_Pull
+	Action_Fail
+	Action_Vendor
+)
+
+type ReproducePkg struct {
+	ImportPath string
+	Action     Action
+	Error      error
+	RolledBack bool
+	Updated    *PkgDep //The dependency as it now is, after an update
+	origin     *origin
+}
+
+//origin is the state of a dependency before reproduceDep changed it, so it
+//can be rolled back.
+type origin struct {
+	dir      string
+	vcs      VCS
+	cloned   bool
+	vendored bool
+	saved    string //The vendored tree before, set aside by saveVendored
+	SHA      string
+	branch   string //Blank when detached or not git
+	pulled   string //The git branch Pull moves, blank if none
+	//pulledSHA is where pulled was before, blank if Pull created it
+	pulledSHA string
+}
+
+type ReproducePkgs []ReproducePkg
+
+//PlanPkg is the action Reproduce would take for a single dependency. FromSHA
+//is blank when the dependency doesn't exist yet, ToSHA when it will be left
+//at whatever it is or pulled to the latest of Branch.
+type PlanPkg struct {
+	ImportPath string
+	Dir        string
+	Action     Action
+	FromSHA    string `json:",omitempty"`
+	ToSHA      string `json:",omitempty"`
+	Tag        string `json:",omitempty"` //Checked out by a semver update
+	Branch     string `json:",omitempty"` //Blank for the remote's default
+	Reason     string `json:",omitempty"`
+}
+
+type PlanPkgs []PlanPkg
+
+func (a ReproducePkgs) Len() int           { return len(a) }
+func (a ReproducePkgs) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a ReproducePkgs) Less(i, j int) bool { return a[i].ImportPath < a[j].ImportPath }
+
+func (a PlanPkgs) Len() int           { return len(a) }
+func (a PlanPkgs) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a PlanPkgs) Less(i, j int) bool { return a[i].ImportPath < a[j].ImportPath }
+
+func (a Action) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strings.ToLower(strings.TrimPrefix(a.String(), "Action_")))
+}
+
+//ReproduceErrors holds every dependency that failed to reproduce.
+type ReproduceErrors []ReproducePkg
+
+func (e ReproduceErrors) Error() string {
+	errStrings := []string{}
+	for _, pkg := range e {
+		errStrings = append(errStrings, pkg.Error.Error())
+	}
+	return strings.Join(errStrings, ", ")
+}
+
+//depDir is where Reproduce puts pkgDep.
+func (c *Context) depDir(workingDir string, pkgDep PkgDep) string {
+	if c.flags.Checked(Vendor) {
+		return filepath.Join(workingDir, "vendor", filepath.FromSlash(pkgDep.ImportPath))
+	}
+	return filepath.Join(c.goPath[0], "src", pkgDep.ImportPath)
+}
+
+//planDep works out what reproduceDep would do to pkgDep, without changing
+//anything.
+func (c *Context) planDep(workingDir string, pkgDep PkgDep, alreadyExists AlreadyExists) PlanPkg {
+	dir := c.depDir(workingDir, pkgDep)
+	r := PlanPkg{ImportPath: pkgDep.ImportPath, Dir: dir, ToSHA: pkgDep.SHA}
+	fail := func(s string, a ...interface{}) PlanPkg {
+		r.Action = Action_Fail
+		r.Reason = fmt.Sprintf(s, a...)
+		return r
+	}
+
+	//Read from the module cache, there is no repository to clone
+	if pkgDep.Module != nil && pkgDep.GitRemote == "" {
+		return fail("Failed to reproduce %s, snapshot taken in module mode, use go mod download for %s@%s.", pkgDep.ImportPath, pkgDep.Module.Path, pkgDep.Module.Version)
+	}
+
+	vcs := c.reproduceVCS.named(pkgDep.VCS)
+	if vcs == nil {
+		return fail("Failed to reproduce %s, unsupported version control system %s.", pkgDep.GitRemote, pkgDep.VCS)
+	}
+
+	//Vendored dependencies are always replaced, there is no git repo to
+	//compare against
+	if c.flags.Checked(Vendor) {
+		if vcs.Name() != "git" {
+			return fail("Failed to vendor %s, only git dependencies can be vendored.", pkgDep.ImportPath)
+		} else if pkgDep.SHA == "" {
+			return fail("Failed to vendor %s, no SHA to vendor.", pkgDep.ImportPath)
+		}
+		r.Action = Action_Vendor
+		return r
+	}
 
 	if !dsutil.CheckPath(dir) {
-		err := c.reproduceGitCtx.Clone(dir, pkgDep.GitRemote)
-		if err != nil {
-			return c.errorf("Failed to produce %s, git clone error in %s: %s.", pkgDep.GitRemote, dir, err.Error())
+		r.Action = Action_Clone
+		if alreadyExists == AlreadyExists_UpdateLatest {
+			r.ToSHA = ""
+			r.Branch = c.branch(pkgDep)
 		}
+		return r
 	} else if alreadyExists == AlreadyExists_Fail {
-		return c.errorf("Failed to reproduce %s, %s already exists.", pkgDep.GitRemote, dir)
-	} else if alreadyExists == AlreadyExists_Force || alreadyExists == AlreadyExists_UpdateLatest {
-		if isGit := c.reproduceGitCtx.IsGit(dir); !isGit {
-			return c.errorf("Falied to reproduce %s, %s is not a git repo.", pkgDep.GitRemote, dir)
-		} else if gitStatus, err := c.reproduceGitCtx.Status(dir); err != nil {
-			return c.errorf("Failed to reproduce %s, could not get git status for %s: %s.", pkgDep.GitRemote, dir, err.Error())
-		} else if gitStatus != git.Clean {
-			return c.errorf("Failed to reproduce %s, git status for %s is %s.", pkgDep.GitRemote, dir, gitStatus.String())
-		} else if err = c.reproduceGitCtx.Checkout(dir, "master"); err != nil {
-			return c.errorf("Failed to checkout master, git pull error in %s: %s.", dir, err.Error())
-		} else if err = c.reproduceGitCtx.Pull(dir); err != nil {
-			return c.errorf("Failed to reproduce %s, git pull error in %s: %s.", pkgDep.GitRemote, dir, err.Error())
-		}
+		return fail("Failed to reproduce %s, %s already exists.", pkgDep.GitRemote, dir)
 	} else if alreadyExists == AlreadyExists_Continue {
-		goto ok
+		r.Action = Action_Skip
+		r.ToSHA = ""
+		return r
+	}
+
+	verb := "reproduce"
+	if alreadyExists == AlreadyExists_Check {
+		verb = "check"
+	}
+
+	//Not being at the head of its branch is fine, it's about to be moved
+	if isRepo := vcs.IsRepo(dir); !isRepo {
+		return fail("Falied to %s %s, %s is not a %s repo.", verb, pkgDep.GitRemote, dir, vcs.Name())
+	} else if status, err := vcs.Status(dir, c.branch(pkgDep)); err != nil {
+		return fail("Failed to %s %s, could not get %s status for %s: %s.", verb, pkgDep.GitRemote, vcs.Name(), dir, err.Error())
+	} else if status != git.Clean && status != git.NotMaster {
+		return fail("Failed to %s %s, %s status for %s is %s.", verb, pkgDep.GitRemote, vcs.Name(), dir, status.String())
+	} else if r.FromSHA, err = vcs.Revision(dir); err != nil {
+		return fail("Failed to %s %s, could not get %s revision for %s: %s.", verb, pkgDep.GitRemote, vcs.Name(), dir, err.Error())
+	}
+
+	if alreadyExists == AlreadyExists_UpdateLatest {
+		r.Action = Action_Pull
+		r.ToSHA = ""
+		if r.Branch = c.branch(pkgDep); r.Branch == "" {
+			r.Branch, _ = vcs.DefaultBranch(dir)
+		}
+
+		//Only the tags already fetched are known here, reproduceDep selects
+		//again once pulled
+		if c.semverUpdate(pkgDep) {
+			if tag, err := c.selectLocalTag(vcs, dir, pkgDep); err != nil {
+				return fail("Failed to update %s in %s: %s", pkgDep.ImportPath, dir, err.Error())
+			} else if tag != nil && tag.revision != r.FromSHA {
+				r.Action = Action_Checkout
+				r.ToSHA = tag.revision
+				r.Tag = tag.tag
+			}
+		}
+	} else if r.FromSHA == pkgDep.SHA {
+		r.Action = Action_Skip
 	} else if alreadyExists == AlreadyExists_Check {
-		if isGit := c.reproduceGitCtx.IsGit(dir); !isGit {
-			return c.errorf("Falied to check %s, %s is not a git repo.", pkgDep.GitRemote, dir)
-		} else if gitStatus, err := c.reproduceGitCtx.Status(dir); err != nil {
-			return c.errorf("Failed to check %s, could not get git status for %s: %s.", pkgDep.GitRemote, dir, err.Error())
-		} else if gitStatus != git.Clean {
-			return c.errorf("Failed to check %s, git status for %s is %s.", pkgDep.GitRemote, dir, gitStatus.String())
-		} else if sha, err := c.reproduceGitCtx.SHA(dir); err != nil {
-			return c.errorf("Failed to check %s, could not get git sha for %s: %s.", pkgDep.GitRemote, dir, err.Error())
-		} else if sha != pkgDep.SHA {
-			return c.errorf("Check %s failed. Expected SHA %s, Have %s.", pkgDep.GitRemote, pkgDep.SHA, sha)
-		} else {
-			goto ok
-		}
-	}
-
-	if alreadyExists != AlreadyExists_UpdateLatest {
-		sha, err = c.reproduceGitCtx.SHA(dir)
+		return fail("Check %s failed. Expected SHA %s, Have %s.", pkgDep.GitRemote, pkgDep.SHA, r.FromSHA)
+	} else {
+		r.Action = Action_Checkout
+	}
+	return r
+}
+
+//reproduceDep returns the origin of the dependency once it has started
+//changing it, even if it then fails.
+func (c *Context) reproduceDep(workingDir string, pkgDep PkgDep, alreadyExists AlreadyExists) (Action, *origin, error) {
+	plan := c.planDep(workingDir, pkgDep, alreadyExists)
+	dir := plan.Dir
+	vcs := c.reproduceVCS.named(pkgDep.VCS)
+	var o *origin
+
+	switch plan.Action {
+	case Action_Fail:
+		return Action_Fail, nil, errors.New(plan.Reason)
+	case Action_Skip:
+		return Action_Skip, nil, nil
+	case Action_Vendor:
+		//Only kept if it may have to be rolled back
+		if c.flags.Checked(Atomic) {
+			o = &origin{dir: dir, vendored: true}
+			var err error
+			if o.saved, err = saveVendored(dir); err != nil {
+				return Action_Fail, nil, fmt.Errorf("Failed to vendor %s, could not set aside %s: %s.", pkgDep.ImportPath, dir, err.Error())
+			}
+		}
+		if err := c.vendorDep(dir, pkgDep); err != nil {
+			return Action_Fail, o, fmt.Errorf("Failed to vendor %s in %s: %s.", pkgDep.ImportPath, dir, err.Error())
+		}
+		return Action_Vendor, o, nil
+	case Action_Clone:
+		o = &origin{dir: dir, vcs: vcs, cloned: true}
+		if err := vcs.Clone(dir, c.remote(pkgDep)); err != nil {
+			return Action_Fail, o, fmt.Errorf("Failed to produce %s, %s clone error in %s: %s.", pkgDep.GitRemote, vcs.Name(), dir, err.Error())
+		} else if plan.ToSHA == "" && plan.Branch != "" {
+			if err := vcs.Checkout(dir, plan.Branch); err != nil {
+				return Action_Fail, o, fmt.Errorf("Failed to reproduce %s, %s error checking out %s in %s: %s.", pkgDep.GitRemote, vcs.Name(), plan.Branch, dir, err.Error())
+			}
+		}
+
+		//Tags can only be chosen from once cloned
+		if alreadyExists == AlreadyExists_UpdateLatest && c.semverUpdate(pkgDep) {
+			tag, err := c.selectLocalTag(vcs, dir, pkgDep)
+			if err != nil {
+				return Action_Fail, o, fmt.Errorf("Failed to update %s in %s: %s", pkgDep.ImportPath, dir, err.Error())
+			} else if tag != nil {
+				plan.ToSHA = tag.revision
+			}
+		}
+	case Action_Checkout, Action_Pull:
+		branch := ""
+		if vcs.Name() == "git" {
+			var err error
+			if branch, err = c.gitBranch(dir); err != nil {
+				return Action_Fail, nil, fmt.Errorf("Failed to reproduce %s, could not get git branch for %s: %s.", pkgDep.GitRemote, dir, err.Error())
+			}
+		}
+		o = &origin{dir: dir, vcs: vcs, SHA: plan.FromSHA, branch: branch}
+		if vcs.Name() == "git" {
+			//Pull checks out and fast-forwards this branch, whatever was
+			//checked out before
+			if o.pulled = plan.Branch; o.pulled == "" {
+				o.pulled, _ = vcs.DefaultBranch(dir)
+			}
+			o.pulledSHA, _ = c.gitExecf(dir, "rev-parse -q --verify 'refs/heads/%s'", o.pulled)
+		}
+
+		if err := vcs.Pull(dir, plan.Branch); err != nil {
+			return Action_Fail, o, fmt.Errorf("Failed to reproduce %s, %s pull error in %s: %s.", pkgDep.GitRemote, vcs.Name(), dir, err.Error())
+		}
+
+		//Pulling fetches any tags published since
+		if alreadyExists == AlreadyExists_UpdateLatest && c.semverUpdate(pkgDep) {
+			tag, err := c.selectLocalTag(vcs, dir, pkgDep)
+			if err != nil {
+				return Action_Fail, o, fmt.Errorf("Failed to update %s in %s: %s", pkgDep.ImportPath, dir, err.Error())
+			} else if tag == nil || tag.revision == plan.FromSHA {
+				if err := c.rollback(o); err != nil {
+					return Action_Fail, o, fmt.Errorf("Failed to update %s, could not return %s to %s: %s.", pkgDep.ImportPath, dir, plan.FromSHA, err.Error())
+				}
+				return Action_Skip, nil, nil
+			}
+			plan.Action = Action_Checkout
+			plan.ToSHA = tag.revision
+		}
+	}
+
+	if plan.ToSHA != "" {
+		sha, err := vcs.Revision(dir)
 		if err != nil {
-			return c.errorf("Failed to reproduce %s, git error getting current SHA in %s: %s.", pkgDep.GitRemote, dir, err.Error())
-		} else if sha == pkgDep.SHA {
+			return Action_Fail, o, fmt.Errorf("Failed to reproduce %s, %s error getting current revision in %s: %s.", pkgDep.GitRemote, vcs.Name(), dir, err.Error())
+		} else if sha == plan.ToSHA {
 
-		} else if err := c.reproduceGitCtx.Checkout(dir, pkgDep.SHA); err != nil {
-			return c.errorf("Failed to reproduce %s, git error in checkout in %s: %s.", pkgDep.GitRemote, dir, err.Error())
+		} else if err := vcs.Checkout(dir, plan.ToSHA); err != nil {
+			return Action_Fail, o, fmt.Errorf("Failed to reproduce %s, %s error in checkout in %s: %s.", pkgDep.GitRemote, vcs.Name(), dir, err.Error())
 		}
 	}
-ok:
-	c.verbosef("%s", pkgDep.ImportPath)
 
-	return nil
+	return plan.Action, o, nil
+}
 
+//currentDep is pkgDep as it is now checked out in dir.
+func (c *Context) currentDep(vcs VCS, dir string, pkgDep PkgDep) (*PkgDep, error) {
+	r := pkgDep
+	var err error
+	if r.SHA, err = vcs.Revision(dir); err != nil {
+		return nil, err
+	} else if r.CommitTime, err = vcs.CommitTime(dir); err != nil {
+		return nil, err
+	} else if r.Tags, err = vcs.Tags(dir); err != nil {
+		return nil, err
+	}
+
+	r.Hash = ""
+	if vcs.Name() == "git" {
+		r.Hash, _ = c.commitHash(dir, r.SHA)
+	}
+	return &r, nil
 }
 
-func (c *Context) Reproduce(workingDir string, depsFile DepsFile, doTests bool, alreadyExists AlreadyExists) error {
-	for _, pkgDep := range depsFile.Deps {
-		err := c.reproduceDep(pkgDep, alreadyExists)
-		if err != nil {
+//rollback returns a dependency to its origin, deleting it if it was cloned.
+//A branch moved by Pull is returned to where it was, or deleted if Pull
+//created it.
+func (c *Context) rollback(o *origin) error {
+	if o.cloned {
+		return os.RemoveAll(o.dir)
+	} else if o.vendored {
+		return restoreVendored(o.dir, o.saved)
+	}
+
+	if o.branch == "" {
+		if err := o.vcs.Checkout(o.dir, o.SHA); err != nil {
 			return err
 		}
+	} else if err := c.reproduceGitCtx.Checkout(o.dir, o.branch); err != nil {
+		return err
+	} else if _, err := c.gitExecf(o.dir, "reset --hard %s", o.SHA); err != nil {
+		return err
+	}
+
+	if o.pulled == "" || o.pulled == o.branch {
+		return nil
+	} else if o.pulledSHA == "" {
+		_, err := c.gitExecf(o.dir, "branch -D '%s'", o.pulled)
+		return err
 	}
+	_, err := c.gitExecf(o.dir, "branch -f '%s' %s", o.pulled, o.pulledSHA)
+	return err
+}
+
+//reproduceLevels splits pkgDeps so that no dependency shares a level with a
+//dependency nested inside it, letting each level be cloned concurrently.
+func reproduceLevels(pkgDeps []PkgDep) [][]PkgDep {
+	levels := [][]PkgDep{}
+	for _, pkgDep := range pkgDeps {
+		level := 0
+		for _, other := range pkgDeps {
+			if other.ImportPath != pkgDep.ImportPath && pkgContains(other.ImportPath, pkgDep.ImportPath) {
+				level++
+			}
+		}
+		for len(levels) <= level {
+			levels = append(levels, []PkgDep{})
+		}
+		levels[level] = append(levels[level], pkgDep)
+	}
+	return levels
+}
+
+//Reproduce clones or checks out every dependency in depsFile, continuing
+//past failures. The returned error is a ReproduceErrors if any failed. With
+//the Atomic flag it finishes the level of nesting a failure happens in, but
+//starts no deeper levels, then rolls back every dependency it changed,
+//including vendored trees it replaced. With AlreadyExists_UpdateLatest each
+//dependency that succeeded has Updated set to its new SHA, CommitTime, Tags
+//and Hash.
+func (c *Context) Reproduce(workingDir string, depsFile DepsFile, doTests bool, alreadyExists AlreadyExists) ([]ReproducePkg, error) {
+	pkgDeps := append([]PkgDep{}, depsFile.Deps...)
 	if doTests {
-		for _, pkgDep := range depsFile.TestDeps {
-			err := c.reproduceDep(pkgDep, alreadyExists)
-			if err != nil {
-				return err
+		pkgDeps = append(pkgDeps, depsFile.TestDeps...)
+	}
+
+	result := []ReproducePkg{}
+	failures := 0
+
+	for _, level := range reproduceLevels(pkgDeps) {
+		if failures != 0 && c.flags.Checked(Atomic) {
+			for _, pkgDep := range level {
+				result = append(result, ReproducePkg{ImportPath: pkgDep.ImportPath, Action: Action_Skip})
+			}
+			continue
+		}
+
+		levelResult := make([]ReproducePkg, len(level))
+		c.parallel(len(level), func(i int) {
+			pkgDep := level[i]
+			action, o, err := c.reproduceDep(workingDir, pkgDep, alreadyExists)
+			levelResult[i] = ReproducePkg{ImportPath: pkgDep.ImportPath, Action: action, Error: err, origin: o}
+
+			if err == nil && alreadyExists == AlreadyExists_UpdateLatest {
+				dir := c.depDir(workingDir, pkgDep)
+				updated, err := c.currentDep(c.reproduceVCS.named(pkgDep.VCS), dir, pkgDep)
+				if err != nil {
+					levelResult[i].Action = Action_Fail
+					levelResult[i].Error = fmt.Errorf("Failed to read updated %s in %s: %s.", pkgDep.ImportPath, dir, err.Error())
+				}
+				levelResult[i].Updated = updated
+			}
+		}, func(i int) {
+			if levelResult[i].Error != nil {
+				levelResult[i].Error = c.errorf("%s", levelResult[i].Error.Error())
+				failures++
+			} else {
+				c.verbosef("%s", levelResult[i].ImportPath)
+			}
+		})
+		result = append(result, levelResult...)
+	}
+
+	if failures != 0 && c.flags.Checked(Atomic) {
+		//Nested dependencies come later, so undo them first
+		for i := len(result) - 1; i >= 0; i-- {
+			if result[i].origin == nil {
+				continue
+			}
+			if err := c.rollback(result[i].origin); err != nil {
+				err = c.errorf("Failed to roll back %s in %s: %s.", result[i].ImportPath, result[i].origin.dir, err.Error())
+				if result[i].Error == nil {
+					result[i].Error = err
+				}
+				continue
+			}
+			result[i].RolledBack = true
+			result[i].Updated = nil
+			c.verbosef("Rolled back %s", result[i].ImportPath)
+		}
+	} else {
+		//Kept, so what was set aside is no longer needed
+		for _, reproducePkg := range result {
+			if o := reproducePkg.origin; o != nil && o.saved != "" {
+				os.RemoveAll(o.saved)
+			}
+		}
+	}
+
+	sort.Sort(ReproducePkgs(result))
+
+	failed := ReproduceErrors{}
+	for _, reproducePkg := range result {
+		if reproducePkg.Error != nil {
+			failed = append(failed, reproducePkg)
+		}
+	}
+	if len(failed) != 0 {
+		return result, failed
+	}
+	return result, nil
+}
+
+//UpdateDepsFile returns depsFile with every dependency that Reproduce
+//updated replaced by how it now is.
+func UpdateDepsFile(depsFile DepsFile, result []ReproducePkg) DepsFile {
+	updated := map[string]PkgDep{}
+	for _, reproducePkg := range result {
+		if reproducePkg.Updated != nil {
+			updated[reproducePkg.ImportPath] = *reproducePkg.Updated
+		}
+	}
+
+	replace := func(pkgDeps []PkgDep) []PkgDep {
+		r := []PkgDep{}
+		for _, pkgDep := range pkgDeps {
+			if updatedDep, ok := updated[pkgDep.ImportPath]; ok {
+				pkgDep = updatedDep
 			}
+			r = append(r, pkgDep)
+		}
+		return r
+	}
+
+	return DepsFile{Deps: replace(depsFile.Deps), TestDeps: replace(depsFile.TestDeps), Graph: depsFile.Graph}
+}
+
+//ChangePkg is a dependency that moved from one revision to another.
+type ChangePkg struct {
+	ImportPath string
+	FromSHA    string
+	ToSHA      string
+	FromTags   []string `json:",omitempty"`
+	ToTags     []string `json:",omitempty"`
+}
+
+//Changes lists every dependency in depsFile that Reproduce updated to a
+//different revision.
+func Changes(depsFile DepsFile, result []ReproducePkg) []ChangePkg {
+	previous := map[string]PkgDep{}
+	for _, pkgDep := range append(append([]PkgDep{}, depsFile.Deps...), depsFile.TestDeps...) {
+		previous[pkgDep.ImportPath] = pkgDep
+	}
+
+	r := []ChangePkg{}
+	for _, reproducePkg := range result {
+		from, ok := previous[reproducePkg.ImportPath]
+		if !ok || reproducePkg.Updated == nil || reproducePkg.Updated.SHA == from.SHA {
+			continue
+		}
+		to := reproducePkg.Updated
+		r = append(r, ChangePkg{reproducePkg.ImportPath, from.SHA, to.SHA, from.Tags, to.Tags})
+	}
+	return r
+}
+
+func (c *Context) PrintChanges(changes []ChangePkg) {
+	maxLen := 0
+	for _, changePkg := range changes {
+		if len(changePkg.ImportPath) > maxLen {
+			maxLen = len(changePkg.ImportPath)
+		}
+	}
+
+	padding := strings.Repeat(" ", maxLen)
+	label := func(sha string, tags []string) string {
+		if len(tags) != 0 {
+			return fmt.Sprintf("%s %v", shortSHA(sha), tags)
 		}
+		return shortSHA(sha)
+	}
+
+	for _, changePkg := range changes {
+		c.format.PrintLine("%s %s -> %s", (changePkg.ImportPath + padding)[0:maxLen],
+			label(changePkg.FromSHA, changePkg.FromTags), label(changePkg.ToSHA, changePkg.ToTags))
+	}
+	if len(changes) == 0 {
+		c.format.PrintLine("No dependencies changed")
+	}
+}
+
+//Plan works out what Reproduce would do to each dependency in depsFile,
+//without changing anything.
+func (c *Context) Plan(workingDir string, depsFile DepsFile, doTests bool, alreadyExists AlreadyExists) []PlanPkg {
+	pkgDeps := append([]PkgDep{}, depsFile.Deps...)
+	if doTests {
+		pkgDeps = append(pkgDeps, depsFile.TestDeps...)
+	}
+
+	result := make([]PlanPkg, len(pkgDeps))
+	c.parallel(len(pkgDeps), func(i int) {
+		result[i] = c.planDep(workingDir, pkgDeps[i], alreadyExists)
+	}, nil)
+
+	sort.Sort(PlanPkgs(result))
+	return result
+}
+
+func (c *Context) actionPrefixes() map[Action]func() {
+	green := c.format.MakePrintf(richtext.Green, richtext.None, richtext.Bold)
+	orange := c.format.MakePrintf(richtext.Orange, richtext.None, richtext.Bold)
+	red := c.format.MakePrintf(richtext.Red, richtext.None, richtext.Bold)
+
+	return map[Action]func(){
+		Action_Skip:     func() { orange("[SKIP ]") },
+		Action_Clone:    func() { green("[CLONE]") },
+		Action_Checkout: func() { green("[CHECK]") },
+		Action_Pull:     func() { green("[PULL ]") },
+		Action_Fail:     func() { red("[FAIL ]") },
+		Action_Vendor:   func() { green("[VEND ]") },
+	}
+}
+
+func (c *Context) PrintPlan(plan []PlanPkg) {
+	maxLen := 0
+	for _, planPkg := range plan {
+		if len(planPkg.ImportPath) > maxLen {
+			maxLen = len(planPkg.ImportPath)
+		}
+	}
+
+	padding := strings.Repeat(" ", maxLen)
+	richPrefix := c.actionPrefixes()
+
+	for _, planPkg := range plan {
+		var message string
+		switch planPkg.Action {
+		case Action_Fail:
+			message = planPkg.Reason
+		case Action_Clone:
+			message = "-> " + shortSHA(planPkg.ToSHA)
+			if planPkg.ToSHA == "" {
+				message = "-> " + branchLabel(planPkg.Branch)
+			}
+		case Action_Checkout:
+			message = shortSHA(planPkg.FromSHA) + " -> " + shortSHA(planPkg.ToSHA)
+			if planPkg.Tag != "" {
+				message = shortSHA(planPkg.FromSHA) + " -> " + planPkg.Tag
+			}
+		case Action_Pull:
+			message = shortSHA(planPkg.FromSHA) + " -> " + branchLabel(planPkg.Branch)
+		case Action_Skip:
+			message = shortSHA(planPkg.FromSHA)
+		case Action_Vendor:
+			message = "-> " + shortSHA(planPkg.ToSHA)
+		}
+
+		richPrefix[planPkg.Action]()
+		c.format.PrintLine(" %s %s", (planPkg.ImportPath + padding)[0:maxLen], message)
+	}
+}
+
+func branchLabel(branch string) string {
+	if branch == "" {
+		return "default branch"
+	}
+	return branch
+}
+
+func WritePlanJson(filename string, plan []PlanPkg) error {
+	jsonOutput, err := json.MarshalIndent(&plan, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeOutput(filename, jsonOutput)
+}
+
+func (c *Context) PrintReproduceSummary(result []ReproducePkg) {
+	maxLen := 0
+	for _, reproducePkg := range result {
+		if len(reproducePkg.ImportPath) > maxLen {
+			maxLen = len(reproducePkg.ImportPath)
+		}
+	}
+
+	padding := strings.Repeat(" ", maxLen)
+	richPrefix := c.actionPrefixes()
+
+	counts := map[Action]int{}
+	rolledBack := 0
+	for _, reproducePkg := range result {
+		counts[reproducePkg.Action]++
+		richPrefix[reproducePkg.Action]()
+		message := ""
+		if reproducePkg.Error != nil {
+			message = reproducePkg.Error.Error()
+		}
+		if reproducePkg.RolledBack {
+			rolledBack++
+			message = strings.TrimSpace("(rolled back) " + message)
+		}
+		c.format.PrintLine(" %s %s", (reproducePkg.ImportPath + padding)[0:maxLen], message)
+	}
+
+	summary := fmt.Sprintf("%d cloned, %d checked out, %d pulled, %d skipped, %d failed",
+		counts[Action_Clone], counts[Action_Checkout], counts[Action_Pull], counts[Action_Skip], counts[Action_Fail])
+	if counts[Action_Vendor] != 0 {
+		summary += fmt.Sprintf(", %d vendored", counts[Action_Vendor])
+	}
+	if rolledBack != 0 {
+		summary += fmt.Sprintf(", %d rolled back", rolledBack)
 	}
-	return nil
+	c.format.PrintLine("%s", summary)
 }
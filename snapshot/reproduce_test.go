@@ -2,8 +2,9 @@ package snapshot_test
 
 import (
 	"bytes"
+	"fmt"
+	"strings"
 	"testing"
-	"time"
 
 	"github.com/desal/dsutil"
 	"github.com/desal/git"
@@ -69,13 +70,16 @@ func main() { fmt.Println(depone.One * deptwo.Two) }
 
 	depsFile := snapshot.DepsFile{
 		Deps: []snapshot.PkgDep{
-			snapshot.PkgDep{"depone", dsutil.PosixPath(m.bareDir) + "/depone", sha1, time.Time{}, nil, nil},
-			snapshot.PkgDep{"deptwo", dsutil.PosixPath(m.bareDir) + "/deptwo", sha2, time.Time{}, nil, nil},
+			snapshot.PkgDep{ImportPath: "depone", GitRemote: dsutil.PosixPath(m.bareDir) + "/depone", SHA: sha1},
+			snapshot.PkgDep{ImportPath: "deptwo", GitRemote: dsutil.PosixPath(m.bareDir) + "/deptwo", SHA: sha2},
 		},
 	}
 
-	err := ctx.Reproduce(m.gopath, depsFile, false, snapshot.AlreadyExists_Fail)
+	result, err := ctx.Reproduce(m.gopath, depsFile, false, snapshot.AlreadyExists_Fail)
 	require.Nil(t, err)
+	require.Equal(t, 2, len(result))
+	assert.Equal(t, snapshot.Action_Clone, result[0].Action)
+	assert.Equal(t, snapshot.Action_Clone, result[1].Action)
 	files, _, _ = m.goCtx.Execf(`find . -not -path "*/.git*"|sort`)
 	assert.Equal(t, `.
 ./src
@@ -92,3 +96,395 @@ func main() { fmt.Println(depone.One * deptwo.Two) }
 
 	assert.Equal(t, "depone\ndeptwo\n", buf.String())
 }
+
+func TestReproduceFailures(t *testing.T) {
+	m := SetupRepos(t)
+	defer m.Close()
+
+	m.AddRepo("depone")
+	m.AddRepo("deptwo")
+	m.AddRepo("depthree")
+
+	m.goCtx.Execf(`
+		cd src/depone;
+		echo 'package depone\n\nconst One = 12' > depone.go;
+		git add -A;
+		git commit -m "gocode";
+		git push;
+		cd ..;
+		rm -rf depone`)
+	m.goCtx.Execf(`
+		cd src/deptwo;
+		echo 'package deptwo\n\nconst Two = 3' > deptwo.go;
+		git add -A;
+		git commit -m "gocode";
+		git push`)
+	m.goCtx.Execf(`
+		cd src/depthree;
+		echo 'package depthree\n\nconst Three = -1' > depthree.go;
+		git add -A;
+		git commit -m "gocode";
+		git push;
+		cd ..;
+		rm -rf depthree`)
+
+	gitCtx := git.New(richtext.Test(t), git.MustPanic)
+	sha1, _ := gitCtx.SHA(m.bareDir + "/depone")
+	sha2, _ := gitCtx.SHA(m.bareDir + "/deptwo")
+
+	ctx := snapshot.New(richtext.Test(t), []string{m.gopath}, snapshot.Jobs(4))
+
+	depsFile := snapshot.DepsFile{
+		Deps: []snapshot.PkgDep{
+			snapshot.PkgDep{ImportPath: "depone", GitRemote: dsutil.PosixPath(m.bareDir) + "/depone", SHA: sha1},
+			snapshot.PkgDep{ImportPath: "deptwo", GitRemote: dsutil.PosixPath(m.bareDir) + "/deptwo", SHA: sha2},
+		},
+		TestDeps: []snapshot.PkgDep{
+			snapshot.PkgDep{ImportPath: "depthree", GitRemote: dsutil.PosixPath(m.bareDir) + "/missing", SHA: sha2},
+		},
+	}
+
+	result, err := ctx.Reproduce(m.gopath, depsFile, true, snapshot.AlreadyExists_Fail)
+	require.NotNil(t, err)
+	failed, ok := err.(snapshot.ReproduceErrors)
+	require.True(t, ok)
+	require.Equal(t, 2, len(failed))
+	assert.Equal(t, "depthree", failed[0].ImportPath)
+	assert.Equal(t, "deptwo", failed[1].ImportPath)
+	assert.Contains(t, failed[1].Error.Error(), "already exists")
+
+	require.Equal(t, 3, len(result))
+	assert.Equal(t, snapshot.Action_Clone, result[0].Action)
+	assert.Equal(t, snapshot.Action_Fail, result[1].Action)
+	assert.Equal(t, snapshot.Action_Fail, result[2].Action)
+
+	buf := &bytes.Buffer{}
+	snapshot.New(richtext.Debug(buf), []string{m.gopath}).PrintReproduceSummary(result)
+	assert.Equal(t, fmt.Sprintf(`[Green,None,[Bold]][CLONE][] depone   
+[Red,None,[Bold]][FAIL ][] depthree %s
+[Red,None,[Bold]][FAIL ][] deptwo   %s
+1 cloned, 0 checked out, 0 pulled, 0 skipped, 2 failed
+`, failed[0].Error, failed[1].Error), buf.String())
+}
+
+func TestPlan(t *testing.T) {
+	m := SetupRepos(t)
+	defer m.Close()
+
+	m.AddRepo("depone")
+	m.AddRepo("deptwo")
+	m.AddRepo("depthree")
+
+	m.goCtx.Execf(`
+		cd src/depone;
+		echo 'package depone\n\nconst One = 12' > depone.go;
+		git add -A;
+		git commit -m "gocode";
+		git push;
+		cd ..;
+		rm -rf depone`)
+	m.goCtx.Execf(`
+		cd src/deptwo;
+		echo 'package deptwo\n\nconst Two = 3' > deptwo.go;
+		git add -A;
+		git commit -m "gocode";
+		git push`)
+	m.goCtx.Execf(`
+		cd src/depthree;
+		echo 'package depthree\n\nconst Three = -1' > depthree.go;
+		git add -A;
+		git commit -m "gocode";
+		git push;
+		echo 'package depthree\n\nconst Three = -2' > depthree.go`)
+
+	gitCtx := git.New(richtext.Test(t), git.MustPanic)
+	sha1, _ := gitCtx.SHA(m.bareDir + "/depone")
+	sha2, _ := gitCtx.SHA(m.bareDir + "/deptwo")
+	sha3, _ := gitCtx.SHA(m.bareDir + "/depthree")
+	initSha2, _ := gitCtx.SHA(m.bareDir + "/deptwo^")
+
+	depsFile := snapshot.DepsFile{
+		Deps: []snapshot.PkgDep{
+			snapshot.PkgDep{ImportPath: "depone", GitRemote: dsutil.PosixPath(m.bareDir) + "/depone", SHA: sha1},
+			snapshot.PkgDep{ImportPath: "deptwo", GitRemote: dsutil.PosixPath(m.bareDir) + "/deptwo", SHA: initSha2},
+			snapshot.PkgDep{ImportPath: "depthree", GitRemote: dsutil.PosixPath(m.bareDir) + "/depthree", SHA: sha3},
+		},
+	}
+
+	ctx := snapshot.New(richtext.Test(t), []string{m.gopath}, snapshot.Jobs(2))
+	plan := ctx.Plan(m.gopath, depsFile, true, snapshot.AlreadyExists_Force)
+	require.Equal(t, 3, len(plan))
+
+	assert.Equal(t, "depone", plan[0].ImportPath)
+	assert.Equal(t, snapshot.Action_Clone, plan[0].Action)
+	assert.Equal(t, sha1, plan[0].ToSHA)
+
+	assert.Equal(t, "depthree", plan[1].ImportPath)
+	assert.Equal(t, snapshot.Action_Fail, plan[1].Action)
+	assert.Contains(t, plan[1].Reason, "Uncommitted")
+
+	assert.Equal(t, "deptwo", plan[2].ImportPath)
+	assert.Equal(t, snapshot.Action_Checkout, plan[2].Action)
+	assert.Equal(t, sha2, plan[2].FromSHA)
+	assert.Equal(t, initSha2, plan[2].ToSHA)
+
+	//Nothing should have been touched
+	files, _, _ := m.goCtx.Execf(`ls src`)
+	assert.Equal(t, "depthree\ndeptwo\n", files)
+
+	plan = ctx.Plan(m.gopath, depsFile, true, snapshot.AlreadyExists_UpdateLatest)
+	assert.Equal(t, snapshot.Action_Pull, plan[2].Action)
+	assert.Equal(t, "", plan[2].ToSHA)
+
+	buf := &bytes.Buffer{}
+	snapshot.New(richtext.Debug(buf), []string{m.gopath}).PrintPlan(plan)
+	assert.Equal(t, fmt.Sprintf(`[Green,None,[Bold]][CLONE][] depone   -> default branch
+[Red,None,[Bold]][FAIL ][] depthree %s
+[Green,None,[Bold]][PULL ][] deptwo   %s -> master
+`, plan[1].Reason, sha2[0:6]), buf.String())
+}
+
+func TestReproduceAtomic(t *testing.T) {
+	m := SetupRepos(t)
+	defer m.Close()
+
+	m.AddRepo("depone")
+	m.AddRepo("deptwo")
+	m.AddRepo("depthree")
+
+	m.goCtx.Execf(`
+		cd src/depone;
+		echo 'package depone\n\nconst One = 12' > depone.go;
+		git add -A;
+		git commit -m "gocode";
+		git push;
+		cd ..;
+		rm -rf depone`)
+	m.goCtx.Execf(`
+		cd src/deptwo;
+		echo 'package deptwo\n\nconst Two = 3' > deptwo.go;
+		git add -A;
+		git commit -m "gocode";
+		git push`)
+	m.goCtx.Execf(`
+		cd src/depthree;
+		echo 'package depthree\n\nconst Three = -1' > depthree.go;
+		git add -A;
+		git commit -m "gocode";
+		git push;
+		echo 'package depthree\n\nconst Three = -2' > depthree.go`)
+
+	gitCtx := git.New(richtext.Test(t), git.MustPanic)
+	sha1, _ := gitCtx.SHA(m.bareDir + "/depone")
+	sha2, _ := gitCtx.SHA(m.bareDir + "/deptwo")
+	sha3, _ := gitCtx.SHA(m.bareDir + "/depthree")
+	initSha2, _ := gitCtx.SHA(m.bareDir + "/deptwo^")
+
+	depsFile := snapshot.DepsFile{
+		Deps: []snapshot.PkgDep{
+			snapshot.PkgDep{ImportPath: "depone", GitRemote: dsutil.PosixPath(m.bareDir) + "/depone", SHA: sha1},
+			snapshot.PkgDep{ImportPath: "deptwo", GitRemote: dsutil.PosixPath(m.bareDir) + "/deptwo", SHA: initSha2},
+			snapshot.PkgDep{ImportPath: "depthree", GitRemote: dsutil.PosixPath(m.bareDir) + "/depthree", SHA: sha3},
+		},
+	}
+
+	ctx := snapshot.New(richtext.Test(t), []string{m.gopath}, snapshot.Atomic)
+	result, err := ctx.Reproduce(m.gopath, depsFile, true, snapshot.AlreadyExists_Force)
+	require.NotNil(t, err)
+	require.Equal(t, 1, len(err.(snapshot.ReproduceErrors)))
+
+	require.Equal(t, 3, len(result))
+	assert.Equal(t, snapshot.Action_Clone, result[0].Action)
+	assert.True(t, result[0].RolledBack)
+	assert.Equal(t, snapshot.Action_Fail, result[1].Action)
+	assert.False(t, result[1].RolledBack)
+	assert.Equal(t, snapshot.Action_Checkout, result[2].Action)
+	assert.True(t, result[2].RolledBack)
+
+	files, _, _ := m.goCtx.Execf(`ls src`)
+	assert.Equal(t, "depthree\ndeptwo\n", files)
+
+	sha, _ := gitCtx.SHA(m.gopath + "/src/deptwo")
+	assert.Equal(t, sha2, sha)
+	branch, _, _ := m.goCtx.Execf(`cd src/deptwo; git rev-parse --abbrev-ref HEAD`)
+	assert.Equal(t, "master\n", branch)
+}
+
+func TestUpdateBranch(t *testing.T) {
+	m := SetupRepos(t)
+	defer m.Close()
+
+	m.AddRepo("depone")
+
+	m.goCtx.Execf(`
+		cd src/depone;
+		echo 'package depone\n\nconst One = 12' > depone.go;
+		git add -A;
+		git commit -m "gocode";
+		git push;
+		git checkout -q -b develop;
+		echo 'package depone\n\nconst One = 24' > depone.go;
+		git add -A;
+		git commit -m "develop";
+		git push -q -u origin develop;
+		cd ..;
+		rm -rf depone`)
+
+	gitCtx := git.New(richtext.Test(t), git.MustPanic)
+	masterSHA, _ := gitCtx.SHA(m.bareDir + "/depone")
+	developSHA, _, _ := m.bareCtx.Execf("cd depone; git rev-parse develop")
+	developSHA = strings.TrimSpace(developSHA)
+
+	depsFile := snapshot.DepsFile{
+		Deps: []snapshot.PkgDep{
+			snapshot.PkgDep{ImportPath: "depone", GitRemote: dsutil.PosixPath(m.bareDir) + "/depone", SHA: masterSHA, Branch: "develop"},
+		},
+	}
+
+	//Cloned, then the recorded branch checked out
+	ctx := snapshot.New(richtext.Test(t), []string{m.gopath})
+	plan := ctx.Plan(m.gopath, depsFile, true, snapshot.AlreadyExists_UpdateLatest)
+	assert.Equal(t, "develop", plan[0].Branch)
+
+	_, err := ctx.Reproduce(m.gopath, depsFile, true, snapshot.AlreadyExists_UpdateLatest)
+	require.Nil(t, err)
+	sha, _ := gitCtx.SHA(m.gopath + "/src/depone")
+	assert.Equal(t, developSHA, sha)
+
+	//Pulled to the configured branch instead
+	config := snapshot.Config{Deps: map[string]snapshot.DepConfig{"depone": {Branch: "master"}}}
+	ctx = snapshot.New(richtext.Test(t), []string{m.gopath}, config)
+	result, err := ctx.Reproduce(m.gopath, depsFile, true, snapshot.AlreadyExists_UpdateLatest)
+	require.Nil(t, err)
+	assert.Equal(t, snapshot.Action_Pull, result[0].Action)
+	sha, _ = gitCtx.SHA(m.gopath + "/src/depone")
+	assert.Equal(t, masterSHA, sha)
+
+	//Nothing recorded or configured follows origin/HEAD
+	depsFile.Deps[0].Branch = ""
+	m.goCtx.Execf("cd src/depone; git checkout -q develop")
+	ctx = snapshot.New(richtext.Test(t), []string{m.gopath})
+	plan = ctx.Plan(m.gopath, depsFile, true, snapshot.AlreadyExists_UpdateLatest)
+	assert.Equal(t, "master", plan[0].Branch)
+}
+
+func TestUpdateRecorded(t *testing.T) {
+	m := SetupRepos(t)
+	defer m.Close()
+
+	m.AddRepo("depone")
+
+	gitCtx := git.New(richtext.Test(t), git.MustPanic)
+	pinnedSHA, _ := gitCtx.SHA(m.gopath + "/src/depone")
+	m.goCtx.Execf(`
+		cd src/depone;
+		echo 'package depone\n\nconst One = 12' > depone.go;
+		git add -A;
+		git commit -m "gocode";
+		git tag -a -m "v1" v1.0.0;
+		git push -q --tags origin master;
+		git checkout -q %s`, pinnedSHA)
+	latestSHA, _ := gitCtx.SHA(m.bareDir + "/depone")
+
+	depsFile := snapshot.DepsFile{
+		Deps: []snapshot.PkgDep{
+			snapshot.PkgDep{ImportPath: "depone", GitRemote: dsutil.PosixPath(m.bareDir) + "/depone", SHA: pinnedSHA},
+		},
+	}
+
+	//Only updates record anything
+	ctx := snapshot.New(richtext.Test(t), []string{m.gopath})
+	result, err := ctx.Reproduce(m.gopath, depsFile, true, snapshot.AlreadyExists_Continue)
+	require.Nil(t, err)
+	assert.Nil(t, result[0].Updated)
+
+	result, err = ctx.Reproduce(m.gopath, depsFile, true, snapshot.AlreadyExists_UpdateLatest)
+	require.Nil(t, err)
+	require.NotNil(t, result[0].Updated)
+
+	updated := *result[0].Updated
+	assert.Equal(t, latestSHA, updated.SHA)
+	assert.Equal(t, []string{"v1.0.0"}, updated.Tags)
+	assert.Equal(t, "ZOezOp7khcaHH4V0Y1minSTX1HmKy2t+c8KtCXl48Eo=", updated.Hash)
+	assert.False(t, updated.CommitTime.IsZero())
+	assert.Equal(t, snapshot.DepsFile{Deps: []snapshot.PkgDep{updated}, TestDeps: []snapshot.PkgDep{}}, snapshot.UpdateDepsFile(depsFile, result))
+
+	//A new tag at the same revision is recorded without being a change
+	m.goCtx.Execf(`cd src/depone; git tag -a -m "v1.0.1" v1.0.1; git push -q --tags`)
+	depsFile.Deps[0] = updated
+	result, err = ctx.Reproduce(m.gopath, depsFile, true, snapshot.AlreadyExists_UpdateLatest)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"v1.0.0", "v1.0.1"}, result[0].Updated.Tags)
+	assert.Equal(t, []snapshot.ChangePkg{}, snapshot.Changes(depsFile, result))
+}
+
+func TestReproduceAtomicBranch(t *testing.T) {
+	m := SetupRepos(t)
+	defer m.Close()
+
+	m.AddRepo("depone")
+	m.AddRepo("deptwo")
+
+	m.goCtx.Execf(`
+		cd src/depone;
+		echo 'package depone\n\nconst One = 12' > depone.go;
+		git add -A;
+		git commit -m "gocode";
+		git push;
+		echo 'package depone\n\nconst One = 24' > depone.go;
+		git add -A;
+		git commit -m "changes";
+		git push;
+		git reset -q --hard HEAD^;
+		git checkout -q --detach`)
+	m.goCtx.Execf(`
+		cd src/deptwo;
+		echo 'package deptwo\n\nconst Two = 3' > deptwo.go;
+		git add -A;
+		git commit -m "gocode";
+		git push;
+		echo 'package deptwo\n\nconst Two = 4' > deptwo.go`)
+
+	gitCtx := git.New(richtext.Test(t), git.MustPanic)
+	oldSHA, _ := gitCtx.SHA(m.gopath + "/src/depone")
+	newSHA, _ := gitCtx.SHA(m.bareDir + "/depone")
+	sha2, _ := gitCtx.SHA(m.bareDir + "/deptwo")
+
+	depsFile := snapshot.DepsFile{
+		Deps: []snapshot.PkgDep{
+			snapshot.PkgDep{ImportPath: "depone", GitRemote: dsutil.PosixPath(m.bareDir) + "/depone", SHA: newSHA},
+			snapshot.PkgDep{ImportPath: "deptwo", GitRemote: dsutil.PosixPath(m.bareDir) + "/deptwo", SHA: sha2},
+		},
+	}
+
+	ctx := snapshot.New(richtext.Test(t), []string{m.gopath}, snapshot.Atomic)
+	result, err := ctx.Reproduce(m.gopath, depsFile, true, snapshot.AlreadyExists_Force)
+	require.NotNil(t, err)
+	assert.True(t, result[0].RolledBack)
+
+	//Back to detached, with master where it was before the pull
+	branch, _, _ := m.goCtx.Execf(`cd src/depone; git rev-parse --abbrev-ref HEAD`)
+	assert.Equal(t, "HEAD\n", branch)
+	sha, _ := gitCtx.SHA(m.gopath + "/src/depone")
+	assert.Equal(t, oldSHA, sha)
+	master, _, _ := m.goCtx.Execf(`cd src/depone; git rev-parse master`)
+	assert.Equal(t, oldSHA+"\n", master)
+}
+
+func TestReproduceModuleOnly(t *testing.T) {
+	m := SetupRepos(t)
+	defer m.Close()
+
+	//As snapshotted from the module cache, with no remote or full SHA
+	depsFile := snapshot.DepsFile{Deps: []snapshot.PkgDep{
+		{ImportPath: "example.com/depone", Tags: []string{"v1.2.0"}, Module: &snapshot.Module{Path: "example.com/depone", Version: "v1.2.0"}},
+	}}
+
+	for _, options := range [][]snapshot.Option{{snapshot.Quiet}, {snapshot.Vendor, snapshot.Quiet}} {
+		ctx := snapshot.New(richtext.Test(t), []string{m.gopath}, options...)
+		result, err := ctx.Reproduce(m.gopath, depsFile, true, snapshot.AlreadyExists_Fail)
+		require.NotNil(t, err)
+		assert.Equal(t, snapshot.Action_Fail, result[0].Action)
+		assert.EqualError(t, result[0].Error, "Failed to reproduce example.com/depone, snapshot taken in module mode, use go mod download for example.com/depone@v1.2.0.")
+	}
+}
@@ -24,96 +24,293 @@ func (c *Context) doneRootDir(dir string) bool {
 	return false
 }
 
-//returns nil for not a dependency
-func (c *Context) scanDep(startingList stringSet, workingDir string, importPath string) *PkgDep {
-	r := &PkgDep{ImportPath: importPath} //Initially create the object with the current importPath, and refine it to the root package if it's possible
+//depScan carries a single dependency through scanDeps. The go list and git
+//calls are made concurrently, while doneDirs deduplication and all output
+//happen serially in sorted order, so the result does not depend on timing.
+type depScan struct {
+	importPath string
+	build      Build //First that required it, listed under
+	skip       bool  //standard library, starting package, ignored or already scanned
+	listErr    error
+	dir        string
+	vcs        VCS //nil if not in a repository
+	topLevel   string
+	branch     string
+	status     git.Status
+	pkgDep     *PkgDep
+}
 
-	if c.goCtx.IsStdLib(importPath) {
-		return nil
+//locateDep finds the directory, version control system and top level of a
+//dependency, without reference to anything else being scanned.
+func (c *Context) locateDep(s *depScan, startingList stringSet, workingDir string) {
+	if c.goCtx.IsStdLib(s.importPath) {
+		s.skip = true
+		return
 	}
 
-	if _, isStartingPkg := startingList[importPath]; isStartingPkg {
-		return nil
+	if _, isStartingPkg := startingList[s.importPath]; isStartingPkg {
+		s.skip = true
+		return
 	}
 
-	//NOTE this is the source of an annoying caveat, when using tags all
-	//dependencies must have a buildable go source file when no tags are
-	//supplied. i.e. 'go list [package]' shouldn't bomb out.
-
-	list, err := c.goCtx.List(workingDir, importPath)
+	//Listed under the build that required it, as it may have no files for
+	//the host platform or without tags
+	var list map[string]map[string]interface{}
+	var err error
+	if s.build == (Build{}) {
+		list, err = c.goCtx.List(workingDir, s.importPath)
+	} else {
+		list, err = c.goList(workingDir, append(c.goPathEnv(), s.build.env()...), s.build.Tags, s.importPath)
+	}
 	if err != nil {
-		r.Error = c.errorf("Failed to scan dependency %s: %s.", importPath, err.Error())
-		return r
+		s.listErr = err
+		return
 	}
 
-	dir := list[importPath]["Dir"].(string)
-
-	if !strings.HasSuffix(filepath.ToSlash(dir), importPath) {
-		r.Error = c.errorf("Falied to scan dependency: directory %s should end in %s.", filepath.ToSlash(dir), importPath)
-		return r
+	s.dir = list[s.importPath]["Dir"].(string)
+	if !s.inImportPath() {
+		return
 	}
 
-	if c.doneRootDir(dir) {
-		return nil
+	s.vcs = c.snapVCS.detect(s.dir)
+	if s.vcs != nil {
+		s.topLevel, _ = s.vcs.TopLevel(s.dir)
 	}
+}
 
-	if !c.snapGitCtx.IsGit(dir) {
-		r.Error = c.errorf("Import %s (%s) is not a git repository", importPath, dir)
-		return r
+func (s *depScan) inImportPath() bool {
+	return strings.HasSuffix(filepath.ToSlash(s.dir), s.importPath)
+}
+
+//claimDep records the repository of a located dependency in doneDirs,
+//marking the dependency skipped if another one already claimed it or its
+//root import path is ignored. Must be called serially in sorted order.
+func (c *Context) claimDep(s *depScan) {
+	if s.skip || s.listErr != nil || !s.inImportPath() {
+		return
 	}
 
-	status, _ := c.snapGitCtx.Status(dir)
-	if status == git.NotMaster {
-		c.warnf("Import %s (%s) is not origin/master", importPath, dir)
-	} else if status != git.Clean {
-		r.Error = c.errorf("Import %s (%s) has git status %s", importPath, dir, status.String())
+	if c.doneRootDir(s.dir) {
+		s.skip = true
+		return
 	}
 
-	topLevel, _ := c.snapGitCtx.TopLevel(dir)
+	if s.vcs == nil {
+		return
+	}
 
 	//Example
 	// dir            = c:\\dev\\golang\\src\\github.com\\desal\\go-snap\\snapshot
 	// topLevel       = c:\\dev\\golang\\src\\github.com\\desal\\go-snap
 	// importPath     = github.com/desal/go-snap/snapshot/snapshot
 	// rootImportPath = github.com/desal/go-snap/snapshot
-	r.ImportPath = importPath[:len(importPath)+len(topLevel)-len(dir)]
-	c.doneDirs[topLevel] = empty{}
-
-	remoteOriginUrl, _ := c.snapGitCtx.RemoteOriginUrl(dir)
-	SHA, _ := c.snapGitCtx.SHA(dir)
-	commitTime, _ := c.snapGitCtx.CommitTime(dir)
-	tags, _ := c.snapGitCtx.Tags(dir)
-
-	r.GitRemote = remoteOriginUrl
-	r.SHA = SHA
-	r.CommitTime = commitTime
-	r.Tags = tags
+	rootImportPath := s.importPath[:len(s.importPath)+len(s.topLevel)-len(s.dir)]
+	c.doneDirs[s.topLevel] = empty{}
+
+	//Matched as withoutIgnored does, against the path it would be recorded as
+	if c.ignored(rootImportPath) {
+		s.skip = true
+		return
+	}
+	s.pkgDep = &PkgDep{ImportPath: rootImportPath}
+}
+
+//inspectDep reads the version control state of a claimed dependency. Content
+//hashes are only recorded for git.
+func (c *Context) inspectDep(s *depScan) {
+	s.branch = c.branch(*s.pkgDep)
+	if s.branch == "" {
+		s.branch, _ = s.vcs.Branch(s.dir)
+	}
+	s.status, _ = s.vcs.Status(s.dir, s.branch)
+
+	remoteOriginUrl, _ := s.vcs.RemoteUrl(s.dir)
+	SHA, _ := s.vcs.Revision(s.dir)
+	commitTime, _ := s.vcs.CommitTime(s.dir)
+	tags, _ := s.vcs.Tags(s.dir)
+	hash := ""
+	if s.vcs.Name() == "git" {
+		hash, _ = c.commitHash(s.topLevel, SHA)
+	}
+
+	s.pkgDep.VCS = vcsName(s.vcs)
+	s.pkgDep.GitRemote = remoteOriginUrl
+	s.pkgDep.SHA = SHA
+	s.pkgDep.Branch = s.branch
+	s.pkgDep.CommitTime = commitTime
+	s.pkgDep.Tags = tags
+	s.pkgDep.Hash = hash
+}
+
+//reportDep reports any problems found scanning a dependency and returns its
+//PkgDep, or nil for not a dependency. One that could not be claimed is
+//recorded by its own import path, so is ignored by that.
+func (c *Context) reportDep(s *depScan) *PkgDep {
+	if s.skip || (s.pkgDep == nil && c.ignored(s.importPath)) {
+		return nil
+	}
+
+	//Initially create the object with the current importPath, refined to the
+	//root package by claimDep if it's possible
+	r := &PkgDep{ImportPath: s.importPath}
+
+	if s.listErr != nil {
+		r.Error = c.errorf("Failed to scan dependency %s: %s.", s.importPath, s.listErr.Error())
+		return r
+	}
+
+	if !s.inImportPath() {
+		r.Error = c.errorf("Falied to scan dependency: directory %s should end in %s.", filepath.ToSlash(s.dir), s.importPath)
+		return r
+	}
+
+	if s.vcs == nil {
+		r.Error = c.errorf("Import %s (%s) is not a git, hg, bzr or svn repository", s.importPath, s.dir)
+		return r
+	}
+
+	r = s.pkgDep
+	if s.status == git.NotMaster {
+		c.warnf("Import %s (%s) is not at the head of %s", s.importPath, s.dir, s.branch)
+	} else if s.status != git.Clean {
+		err := StatusError{"Import", s.importPath, s.dir, s.status}
+		if c.dirtyAllowed(s.importPath, r.ImportPath) {
+			c.warnf("%s, allowed", err.Error())
+		} else {
+			c.errorf("%s", err.Error())
+			r.Error = err
+		}
+	}
+
 	if r.Error == nil {
-		c.verbosef("%s", importPath)
+		c.verbosef("%s", s.importPath)
 	}
 	return r
 }
 
+//scanDeps scans each set of dependencies, sharing doneDirs between them so a
+//repository is only recorded against the first set that uses it. Each is
+//located under its build in depBuilds, the default if missing. Those behind
+//the head of their branch are also returned.
+func (c *Context) scanDeps(startingList stringSet, workingDir string, depBuilds map[string]Build, depSets ...stringSet) ([][]PkgDep, stringSet) {
+	scans := [][]*depScan{}
+	all := []*depScan{}
+	for _, deps := range depSets {
+		set := []*depScan{}
+		for _, dep := range deps.Sorted() {
+			s := &depScan{importPath: dep, build: depBuilds[dep]}
+			set = append(set, s)
+			all = append(all, s)
+		}
+		scans = append(scans, set)
+	}
+
+	c.parallel(len(all), func(i int) { c.locateDep(all[i], startingList, workingDir) }, nil)
+
+	claimed := []*depScan{}
+	for _, s := range all {
+		c.claimDep(s)
+		if s.pkgDep != nil {
+			claimed = append(claimed, s)
+		}
+	}
+
+	c.parallel(len(claimed), func(i int) { c.inspectDep(claimed[i]) }, nil)
+
+	r := [][]PkgDep{}
+	notHead := stringSet{}
+	for _, set := range scans {
+		pkgDeps := []PkgDep{}
+		for _, s := range set {
+			if pkgDep := c.reportDep(s); pkgDep != nil {
+				pkgDeps = append(pkgDeps, *pkgDep)
+				if s.status == git.NotMaster {
+					notHead[pkgDep.ImportPath] = empty{}
+				}
+			}
+		}
+		r = append(r, pkgDeps)
+	}
+	return r, notHead
+}
+
 //pkg string should be a space delimited list of packages including all subfolders
 //typically ./...
+//
+//Inside a module-aware project each dependency is recorded against the module
+//that provides it, rather than requiring a git checkout under GOPATH.
+//
+//Packages are listed with each of tagsets for each of the Platforms, and
+//when that is more than the default the Builds requiring each dependency
+//are recorded.
+//
+//With the ImportGraph flag the imports between repositories are recorded as
+//the Graph, rooted at each repository of pkgString.
 func (c *Context) Snapshot(workingDir, pkgString string, tagsets []string) (DepsFile, error) {
+	r, _, err := c.snapshot(workingDir, pkgString, tagsets)
+	return r, err
+}
+
+//snapshot is Snapshot, also returning the dependencies behind the head of
+//their branch, which are not recorded.
+func (c *Context) snapshot(workingDir, pkgString string, tagsets []string) (DepsFile, stringSet, error) {
 
 	initialPackages := stringSet{}
 	regDeps := stringSet{}
 	testDeps := stringSet{}
 
-	for _, tags := range tagsets {
-		var goListCtx *gocmd.Context
+	modules, err := c.listModules(workingDir)
+	if err != nil {
+		return DepsFile{}, nil, c.errorf("Failed to run go list -m: %s", err.Error())
+	}
 
-		if c.flags.Checked(SkipVendor) {
-			goListCtx = gocmd.New(c.format, c.goPath, tags, "", gocmd.SkipVendor)
+	builds := c.builds(tagsets)
+	buildDeps := make([]stringSet, len(builds))
+	roots := stringSet{}
+	pkgImports := map[string]stringSet{}
+	addImports := func(pkg string, e map[string]interface{}, fields ...string) {
+		if _, ok := pkgImports[pkg]; !ok {
+			pkgImports[pkg] = stringSet{}
+		}
+		for _, field := range fields {
+			if importsInt, ok := e[field]; ok {
+				for _, imported := range importsInt.([]interface{}) {
+					pkgImports[pkg][imported.(string)] = empty{}
+				}
+			}
+		}
+	}
+	for i, build := range builds {
+		var listPkgs func(pkgString string) (map[string]map[string]interface{}, error)
+		buildDeps[i] = stringSet{}
+
+		if modules != nil {
+			build := build
+			listPkgs = func(pkgString string) (map[string]map[string]interface{}, error) {
+				return c.goList(workingDir, build.env(), build.Tags, pkgString)
+			}
+		} else if build.Platform != "" {
+			//gocmd only lists for the host platform
+			env := append(c.goPathEnv(), build.env()...)
+			tags := build.Tags
+			listPkgs = func(pkgString string) (map[string]map[string]interface{}, error) {
+				return c.goList(workingDir, env, tags, pkgString)
+			}
 		} else {
-			goListCtx = gocmd.New(c.format, c.goPath, tags, "")
+			var goListCtx *gocmd.Context
+			if c.flags.Checked(SkipVendor) {
+				goListCtx = gocmd.New(c.format, c.goPath, build.Tags, "", gocmd.SkipVendor)
+			} else {
+				goListCtx = gocmd.New(c.format, c.goPath, build.Tags, "")
+			}
+			listPkgs = func(pkgString string) (map[string]map[string]interface{}, error) {
+				return goListCtx.List(workingDir, pkgString)
+			}
 		}
 
-		list, err := goListCtx.List(workingDir, pkgString)
+		list, err := listPkgs(pkgString)
 		if err != nil {
-			return DepsFile{}, c.errorf("Failed to run go list: %s", err.Error())
+			return DepsFile{}, nil, c.errorf("Failed to run go list: %s", err.Error())
 		}
 
 		allTestImports := stringSet{}
@@ -121,20 +318,27 @@ func (c *Context) Snapshot(workingDir, pkgString string, tagsets []string) (Deps
 			initialPackages[pkg] = empty{}
 			dir := e["Dir"].(string)
 
-			if !c.doneRootDir(dir) {
+			if modules == nil && !c.doneRootDir(dir) {
 				//In case the root dir itself doesn't contain any .go files (only sub
 				//packages).
 
-				if !c.snapGitCtx.IsGit(dir) {
-					return DepsFile{}, fmt.Errorf("All scanned directories must be in a git repo")
+				vcs := c.snapVCS.detect(dir)
+				if vcs == nil {
+					return DepsFile{}, nil, fmt.Errorf("All scanned directories must be in a git, hg, bzr or svn repo")
 				}
 
-				topLevel, err := c.snapGitCtx.TopLevel(dir)
+				topLevel, err := vcs.TopLevel(dir)
 				if err != nil {
-					return DepsFile{}, err
+					return DepsFile{}, nil, err
 				}
 				c.doneDirs[topLevel] = empty{}
+				if rootLen := len(pkg) + len(topLevel) - len(dir); rootLen > 0 && rootLen <= len(pkg) {
+					roots[pkg[:rootLen]] = empty{}
+				}
+			}
 
+			if c.flags.Checked(ImportGraph) {
+				addImports(pkg, e, "Imports", "TestImports", "XTestImports")
 			}
 
 			c.doneDirs[dir] = empty{}
@@ -157,6 +361,7 @@ func (c *Context) Snapshot(workingDir, pkgString string, tagsets []string) (Deps
 				deps := depsInt.([]interface{})
 				for _, dep := range deps {
 					regDeps[dep.(string)] = empty{}
+					buildDeps[i][dep.(string)] = empty{}
 				}
 			}
 		}
@@ -167,7 +372,7 @@ func (c *Context) Snapshot(workingDir, pkgString string, tagsets []string) (Deps
 		}
 
 		if len(allTestImportList) > 0 {
-			testList, _ := goListCtx.List(workingDir, strings.Join(allTestImportList, " "))
+			testList, _ := listPkgs(strings.Join(allTestImportList, " "))
 			for _, e := range testList {
 				if depsInt, ok := e["Deps"]; ok {
 					deps := depsInt.([]interface{})
@@ -176,6 +381,7 @@ func (c *Context) Snapshot(workingDir, pkgString string, tagsets []string) (Deps
 					}
 
 					for _, dep := range allTestImportList {
+						buildDeps[i][dep] = empty{}
 						if _, isRegDep := regDeps[dep]; isRegDep {
 							continue
 						}
@@ -184,23 +390,58 @@ func (c *Context) Snapshot(workingDir, pkgString string, tagsets []string) (Deps
 				}
 			}
 		}
-	}
 
-	scanDeps := func(deps stringSet) []PkgDep {
-		r := []PkgDep{}
-		for _, dep := range deps.Sorted() {
-			if pkgDep := c.scanDep(initialPackages, workingDir, dep); pkgDep != nil {
-				r = append(r, *pkgDep)
+		if c.flags.Checked(ImportGraph) {
+			graphDeps := []string{}
+			for _, dep := range buildDeps[i].Sorted() {
+				if !c.goCtx.IsStdLib(dep) {
+					graphDeps = append(graphDeps, dep)
+				}
+			}
+			if len(graphDeps) > 0 {
+				depList, err := listPkgs(strings.Join(graphDeps, " "))
+				if err != nil {
+					c.warnf("Failed to list imports for the import graph: %s", err.Error())
+				}
+				for pkg, e := range depList {
+					addImports(pkg, e, "Imports")
+				}
 			}
 		}
-		return r
 	}
 
+	var scanned [][]PkgDep
+	var notHead stringSet
+	if modules != nil {
+		scanned, notHead = c.scanModules(modules, regDeps, testDeps)
+	} else {
+		//The first build that required each dependency
+		depBuilds := map[string]Build{}
+		for i := len(builds) - 1; i >= 0; i-- {
+			for dep := range buildDeps[i] {
+				depBuilds[dep] = builds[i]
+			}
+		}
+		scanned, notHead = c.scanDeps(initialPackages, workingDir, depBuilds, regDeps, testDeps)
+	}
 	r := DepsFile{
-		Deps:     scanDeps(regDeps),
-		TestDeps: scanDeps(testDeps),
+		Deps:     scanned[0],
+		TestDeps: scanned[1],
 	}
 
+	addBuilds(r.Deps, builds, buildDeps)
+	addBuilds(r.TestDeps, builds, buildDeps)
+	if c.flags.Checked(ImportGraph) {
+		if modules != nil {
+			roots = stringSet{}
+			for _, module := range modules.modules {
+				if module.Main {
+					roots[module.Path] = empty{}
+				}
+			}
+		}
+		r.Graph = newGraph(roots, pkgImports, initialPackages, r)
+	}
 	r.Sort()
 
 	errStrings := []string{}
@@ -214,9 +455,8 @@ func (c *Context) Snapshot(workingDir, pkgString string, tagsets []string) (Deps
 
 	appendErrs(r.Deps)
 	appendErrs(r.TestDeps)
-	var err error
 	if len(errStrings) != 0 {
 		err = errors.New(strings.Join(errStrings, ", "))
 	}
-	return r, err
+	return r, notHead, err
 }
@@ -111,9 +111,9 @@ func main() { fmt.Println(depone.One * deptwo.Two) }
 	stripTime(&depsFile)
 	assert.Equal(t,
 		fmt.Sprintf("{["+
-			"{depone %s/depone %s 0001-01-01 00:00:00 +0000 UTC [] <nil>} "+
-			"{deptwo %s/deptwo %s 0001-01-01 00:00:00 +0000 UTC [] <nil>}"+
-			"] []}", dsutil.PosixPath(m.bareDir), sha1, dsutil.PosixPath(m.bareDir), sha2),
+			"{depone  %s/depone %s master 0001-01-01 00:00:00 +0000 UTC [] ZOezOp7khcaHH4V0Y1minSTX1HmKy2t+c8KtCXl48Eo= <nil> [] <nil>} "+
+			"{deptwo  %s/deptwo %s master 0001-01-01 00:00:00 +0000 UTC [] 81OXN4DU2jzDhPAnL11s0Q6nxDZSauIoRE/dq4sP5gg= <nil> [] <nil>}"+
+			"] [] <nil>}", dsutil.PosixPath(m.bareDir), sha1, dsutil.PosixPath(m.bareDir), sha2),
 		fmt.Sprintf("%v", depsFile))
 
 	assert.Equal(t, "depone\ndeptwo\n", buf.String())
@@ -175,9 +175,9 @@ func TestA(t *testing.T) { t.Log(depone.One * depone.Two) }
 
 	assert.Equal(t,
 		fmt.Sprintf("{[] ["+
-			"{depone %s/depone %s 0001-01-01 00:00:00 +0000 UTC [] <nil>} "+
-			"{deptwo %s/deptwo %s 0001-01-01 00:00:00 +0000 UTC [] <nil>}"+
-			"]}", dsutil.PosixPath(m.bareDir), sha1, dsutil.PosixPath(m.bareDir), sha2),
+			"{depone  %s/depone %s master 0001-01-01 00:00:00 +0000 UTC [] ZOezOp7khcaHH4V0Y1minSTX1HmKy2t+c8KtCXl48Eo= <nil> [] <nil>} "+
+			"{deptwo  %s/deptwo %s master 0001-01-01 00:00:00 +0000 UTC [] 81OXN4DU2jzDhPAnL11s0Q6nxDZSauIoRE/dq4sP5gg= <nil> [] <nil>}"+
+			"] <nil>}", dsutil.PosixPath(m.bareDir), sha1, dsutil.PosixPath(m.bareDir), sha2),
 		fmt.Sprintf("%v", depsFile))
 
 	assert.Equal(t, "depone\ndeptwo\n", buf.String())
@@ -239,9 +239,9 @@ func TestA(t *testing.T) { t.Log(depone.One * depone.Two) }
 
 	assert.Equal(t,
 		fmt.Sprintf("{[] ["+
-			"{depone %s/depone %s 0001-01-01 00:00:00 +0000 UTC [] <nil>} "+
-			"{deptwo %s/deptwo %s 0001-01-01 00:00:00 +0000 UTC [] <nil>}"+
-			"]}", dsutil.PosixPath(m.bareDir), sha1, dsutil.PosixPath(m.bareDir), sha2),
+			"{depone  %s/depone %s master 0001-01-01 00:00:00 +0000 UTC [] ZOezOp7khcaHH4V0Y1minSTX1HmKy2t+c8KtCXl48Eo= <nil> [] <nil>} "+
+			"{deptwo  %s/deptwo %s master 0001-01-01 00:00:00 +0000 UTC [] 81OXN4DU2jzDhPAnL11s0Q6nxDZSauIoRE/dq4sP5gg= <nil> [] <nil>}"+
+			"] <nil>}", dsutil.PosixPath(m.bareDir), sha1, dsutil.PosixPath(m.bareDir), sha2),
 		fmt.Sprintf("%v", depsFile))
 
 	assert.Equal(t, "depone\ndeptwo\n", buf.String())
@@ -297,9 +297,9 @@ func main() { fmt.Println(depone.One * deptwo.Two) }
 
 	assert.Equal(t,
 		fmt.Sprintf("{["+
-			"{depone %s/depone %s 0001-01-01 00:00:00 +0000 UTC [v1.0] <nil>} "+
-			"{deptwo %s/deptwo %s 0001-01-01 00:00:00 +0000 UTC [v1.0 vAwesome] <nil>}"+
-			"] []}", dsutil.PosixPath(m.bareDir), sha1, dsutil.PosixPath(m.bareDir), sha2),
+			"{depone  %s/depone %s master 0001-01-01 00:00:00 +0000 UTC [v1.0] ZOezOp7khcaHH4V0Y1minSTX1HmKy2t+c8KtCXl48Eo= <nil> [] <nil>} "+
+			"{deptwo  %s/deptwo %s master 0001-01-01 00:00:00 +0000 UTC [v1.0 vAwesome] 81OXN4DU2jzDhPAnL11s0Q6nxDZSauIoRE/dq4sP5gg= <nil> [] <nil>}"+
+			"] [] <nil>}", dsutil.PosixPath(m.bareDir), sha1, dsutil.PosixPath(m.bareDir), sha2),
 		fmt.Sprintf("%v", depsFile))
 
 	assert.Equal(t, "depone\ndeptwo\n", buf.String())
@@ -387,3 +387,242 @@ func main() { fmt.Println(depone.One * deptwo.Two * depthree.Three) }
 
 	assert.Equal(t, fmt.Sprintf("[WARN]%s[]\n[WARN]%s[]\ndeptwo\n", depsFile.Deps[0].Error, depsFile.Deps[1].Error), buf.String())
 }
+
+func TestSnapshotJobs(t *testing.T) {
+	m := SetupRepos(t)
+	defer m.Close()
+
+	m.AddRepo("depone")
+	m.AddRepo("deptwo")
+	m.AddRepo("depthree")
+	m.AddRepo("mainpkg")
+
+	m.goCtx.Execf(`
+		cd src/depone;
+		echo 'package depone\n\nconst One = 12' > depone.go;
+		mkdir sub;
+		echo 'package sub\n\nconst Sub = 1' > sub/sub.go;
+		git add -A;
+		git commit -m "gocode";
+		git push`)
+
+	m.goCtx.Execf(`
+		cd src/deptwo;
+		echo 'package deptwo\n\nconst Two = 3' > deptwo.go;
+		git add -A;
+		git commit -m "gocode";
+		git push`)
+
+	m.goCtx.Execf(`
+		cd src/depthree;
+		echo 'package depthree\n\nconst Three = -1' > depthree.go;
+		git add -A;
+		git commit -m "gocode";
+		git push;
+		echo 'package depthree\n\nconst Three = -2' > depthree.go;`)
+
+	m.goCtx.Execf(`
+		cd src/mainpkg;
+		echo '%s' > main.go;
+		git add -A;
+		git commit -m "gocode";
+		git push`, `package main
+
+import (
+	"fmt"
+	"depone"
+	"depone/sub"
+	"deptwo"
+	"depthree"
+)
+
+func main() { fmt.Println(depone.One * sub.Sub * deptwo.Two * depthree.Three) }
+`)
+
+	serialBuf := &bytes.Buffer{}
+	serialCtx := snapshot.New(richtext.Debug(serialBuf), []string{m.gopath}, snapshot.Verbose)
+	serialDeps, serialErr := serialCtx.Snapshot(m.gopath, "mainpkg", []string{""})
+	require.NotNil(t, serialErr)
+
+	for i := 0; i < 5; i++ {
+		buf := &bytes.Buffer{}
+		ctx := snapshot.New(richtext.Debug(buf), []string{m.gopath}, snapshot.Verbose, snapshot.Jobs(4))
+		depsFile, err := ctx.Snapshot(m.gopath, "mainpkg", []string{""})
+		require.NotNil(t, err)
+
+		assert.Equal(t, serialErr.Error(), err.Error())
+		assert.Equal(t, fmt.Sprintf("%v", serialDeps), fmt.Sprintf("%v", depsFile))
+		assert.Equal(t, serialBuf.String(), buf.String())
+	}
+
+	require.Equal(t, 3, len(serialDeps.Deps))
+	assert.Equal(t, "depone", serialDeps.Deps[0].ImportPath)
+	assert.Equal(t, "depthree", serialDeps.Deps[1].ImportPath)
+	assert.Equal(t, "deptwo", serialDeps.Deps[2].ImportPath)
+	assert.Equal(t, fmt.Sprintf("depone\n[WARN]%s[]\ndeptwo\n", serialDeps.Deps[1].Error), serialBuf.String())
+}
+
+func TestSnapshotModules(t *testing.T) {
+	m := SetupRepos(t)
+	defer m.Close()
+
+	m.AddRepo("depone")
+	m.AddRepo("mainpkg")
+
+	m.goCtx.Execf(`
+		cd src/depone;
+		echo 'module example.com/depone\n\ngo 1.12' > go.mod;
+		echo 'package depone\n\nconst One = 12' > depone.go;
+		git add -A;
+		git commit -m "gocode";
+		git tag v1.0.0;
+		git push`)
+	m.goCtx.Execf(`
+		cd src/mainpkg;
+		echo 'module example.com/mainpkg\n\ngo 1.12\n\nrequire example.com/depone v1.0.0\n\nreplace example.com/depone => ../depone' > go.mod;
+		echo '%s' > main.go;
+		git add -A;
+		git commit -m "gocode";
+		git push`, `package main
+
+import (
+	"fmt"
+	"example.com/depone"
+)
+
+func main() { fmt.Println(depone.One) }
+`)
+
+	buf := &bytes.Buffer{}
+	ctx := snapshot.New(richtext.Debug(buf), []string{m.gopath}, snapshot.Verbose)
+	depsFile, err := ctx.Snapshot(m.gopath+"/src/mainpkg", "./...", []string{""})
+	require.Nil(t, err)
+
+	gitCtx := git.New(richtext.Test(t), git.MustPanic)
+	sha1, _ := gitCtx.SHA(m.bareDir + "/depone")
+
+	require.Equal(t, 1, len(depsFile.Deps))
+	assert.Equal(t, 0, len(depsFile.TestDeps))
+	assert.Equal(t, "example.com/depone", depsFile.Deps[0].ImportPath)
+	assert.Equal(t, dsutil.PosixPath(m.bareDir)+"/depone", depsFile.Deps[0].GitRemote)
+	assert.Equal(t, sha1, depsFile.Deps[0].SHA)
+	assert.Equal(t, []string{"v1.0.0"}, depsFile.Deps[0].Tags)
+	assert.Equal(t, &snapshot.Module{Path: "example.com/depone", Replace: "../depone"}, depsFile.Deps[0].Module)
+
+	assert.Equal(t, "example.com/depone\n", buf.String())
+}
+
+func TestSnapshotBranch(t *testing.T) {
+	m := SetupRepos(t)
+	defer m.Close()
+
+	m.AddRepo("depone")
+	m.AddRepo("deptwo")
+	m.AddRepo("mainpkg")
+
+	m.goCtx.Execf(`
+		cd src/depone;
+		git checkout -q -b develop;
+		echo 'package depone\n\nconst One = 12' > depone.go;
+		git add -A;
+		git commit -m "gocode";
+		git push -q -u origin develop`)
+	m.goCtx.Execf(`
+		cd src/deptwo;
+		echo 'package deptwo\n\nconst Two = 3' > deptwo.go;
+		git add -A;
+		git commit -m "gocode";
+		git push;
+		git checkout -q HEAD^0`)
+	m.goCtx.Execf(`
+		cd src/mainpkg;
+		echo '%s' > main.go;
+		git add -A;
+		git commit -m "gocode";
+		git push`, `package main
+
+import (
+	"fmt"
+	"depone"
+	"deptwo"
+)
+
+func main() { fmt.Println(depone.One * deptwo.Two) }
+`)
+
+	buf := &bytes.Buffer{}
+	ctx := snapshot.New(richtext.Debug(buf), []string{m.gopath}, snapshot.Warn)
+	depsFile, err := ctx.Snapshot(m.gopath, "mainpkg", []string{""})
+	require.Nil(t, err)
+
+	//Detached deptwo follows the remote's default branch
+	require.Equal(t, 2, len(depsFile.Deps))
+	assert.Equal(t, "develop", depsFile.Deps[0].Branch)
+	assert.Equal(t, "master", depsFile.Deps[1].Branch)
+	assert.Equal(t, "", buf.String())
+
+	//Configured to follow master, which depone is ahead of
+	buf.Reset()
+	config := snapshot.Config{Deps: map[string]snapshot.DepConfig{"depone": {Branch: "master"}}}
+	ctx = snapshot.New(richtext.Debug(buf), []string{m.gopath}, snapshot.Warn, config)
+	depsFile, err = ctx.Snapshot(m.gopath, "mainpkg", []string{""})
+	require.Nil(t, err)
+
+	assert.Equal(t, "master", depsFile.Deps[0].Branch)
+	assert.Equal(t, fmt.Sprintf("[WARN]Import depone (%s/src/depone) is not at the head of master[]\n", m.gopath), buf.String())
+}
+
+func TestSnapshotIgnoreAllowDirty(t *testing.T) {
+	m := SetupRepos(t)
+	defer m.Close()
+
+	m.AddRepo("depone")
+	m.AddRepo("deptwo")
+	m.AddRepo("mainpkg")
+
+	m.goCtx.Execf(`
+		cd src/depone;
+		echo 'package depone\n\nconst One = 12' > depone.go;
+		git add -A;
+		git commit -m "gocode";
+		git push`)
+	m.goCtx.Execf(`
+		cd src/deptwo;
+		mkdir sub;
+		echo 'package sub\n\nconst Two = 3' > sub/sub.go;
+		git add -A;
+		git commit -m "gocode";
+		git push`)
+	//Only a package below the root of deptwo is used, it is still ignored
+	m.goCtx.Execf(`
+		cd src/mainpkg;
+		echo 'package main\n\nimport (\n\t"depone"\n\t"deptwo/sub"\n)\n\nvar _ = depone.One * sub.Two' > main.go;
+		git add -A;
+		git commit -m "gocode";
+		git push`)
+
+	ctx := snapshot.New(richtext.Test(t), []string{m.gopath})
+	depsFile, err := ctx.Snapshot(m.gopath, "mainpkg", []string{""})
+	require.Nil(t, err)
+
+	m.goCtx.Execf(`echo 'extra' > src/depone/extra.txt`)
+	m.goCtx.Execf(`echo 'extra' > src/deptwo/extra.txt`)
+
+	ctx = snapshot.New(richtext.Test(t), []string{m.gopath})
+	_, err = ctx.Snapshot(m.gopath, "mainpkg", []string{""})
+	assert.NotNil(t, err)
+
+	options := []snapshot.Option{snapshot.Ignore{"deptwo"}, snapshot.AllowDirty{"dep..."}}
+	ctx = snapshot.New(richtext.Test(t), []string{m.gopath}, options...)
+	dirtyFile, err := ctx.Snapshot(m.gopath, "mainpkg", []string{""})
+	require.Nil(t, err)
+	require.Equal(t, 1, len(dirtyFile.Deps))
+	assert.Equal(t, depsFile.Deps[0], dirtyFile.Deps[0])
+
+	//deptwo is still in the snapshot, but not reported as no longer required
+	buf := &bytes.Buffer{}
+	ctx = snapshot.New(richtext.Debug(buf), []string{m.gopath}, options...)
+	_, ok := ctx.Compare(m.gopath, "mainpkg", []string{""}, depsFile, true)
+	assert.True(t, ok)
+	assert.Equal(t, "[Green,None,[Bold]][ OK ][] depone \n", buf.String())
+}
@@ -0,0 +1,65 @@
+package snapshot
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+//importPathMatcher matches import paths against a pattern in which ...
+//matches any string, as go list does. A pattern ending in /... also matches
+//the path before it, so github.com/desal/... matches github.com/desal.
+func importPathMatcher(pattern string) func(string) bool {
+	trimmed := strings.TrimSuffix(pattern, "/...")
+	expr := strings.Replace(regexp.QuoteMeta(trimmed), `\.\.\.`, `.*`, -1)
+	if trimmed != pattern {
+		expr += `(/.*)?`
+	}
+	return regexp.MustCompile("^" + expr + "$").MatchString
+}
+
+//matchesAny is true if importPath matches any of patterns.
+func matchesAny(patterns []string, importPath string) bool {
+	for _, pattern := range patterns {
+		if importPathMatcher(pattern)(importPath) {
+			return true
+		}
+	}
+	return false
+}
+
+//SelectDeps returns the dependencies in depsFile that match any of patterns,
+//each an import path or a glob such as github.com/desal/... It is an error
+//for a pattern to match nothing.
+func SelectDeps(depsFile DepsFile, patterns []string) (DepsFile, error) {
+	matchers := make([]func(string) bool, len(patterns))
+	matched := make([]bool, len(patterns))
+	for i, pattern := range patterns {
+		matchers[i] = importPathMatcher(pattern)
+	}
+
+	selectPkgDeps := func(pkgDeps []PkgDep) []PkgDep {
+		r := []PkgDep{}
+		for _, pkgDep := range pkgDeps {
+			selected := false
+			for i, matcher := range matchers {
+				if matcher(pkgDep.ImportPath) {
+					matched[i] = true
+					selected = true
+				}
+			}
+			if selected {
+				r = append(r, pkgDep)
+			}
+		}
+		return r
+	}
+
+	r := DepsFile{Deps: selectPkgDeps(depsFile.Deps), TestDeps: selectPkgDeps(depsFile.TestDeps)}
+	for i, pattern := range patterns {
+		if !matched[i] {
+			return DepsFile{}, fmt.Errorf("No dependency matches '%s'.", pattern)
+		}
+	}
+	return r, nil
+}
@@ -0,0 +1,104 @@
+package snapshot_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/desal/dsutil"
+	"github.com/desal/go-snap/snapshot"
+	"github.com/desal/richtext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectDeps(t *testing.T) {
+	depsFile := snapshot.DepsFile{
+		Deps: []snapshot.PkgDep{
+			{ImportPath: "github.com/desal"},
+			{ImportPath: "github.com/desal/git"},
+			{ImportPath: "github.com/desalination/pump"},
+			{ImportPath: "gopkg.in/yaml.v2"},
+		},
+		TestDeps: []snapshot.PkgDep{
+			{ImportPath: "github.com/desal/testdep"},
+			{ImportPath: "github.com/stretchr/testify"},
+		},
+	}
+
+	importPaths := func(pkgDeps []snapshot.PkgDep) []string {
+		r := []string{}
+		for _, pkgDep := range pkgDeps {
+			r = append(r, pkgDep.ImportPath)
+		}
+		return r
+	}
+
+	selected, err := snapshot.SelectDeps(depsFile, []string{"github.com/desal/..."})
+	require.Nil(t, err)
+	assert.Equal(t, []string{"github.com/desal", "github.com/desal/git"}, importPaths(selected.Deps))
+	assert.Equal(t, []string{"github.com/desal/testdep"}, importPaths(selected.TestDeps))
+
+	selected, err = snapshot.SelectDeps(depsFile, []string{"gopkg.in/yaml.v2", "github.com/desal..."})
+	require.Nil(t, err)
+	assert.Equal(t, []string{"github.com/desal", "github.com/desal/git", "github.com/desalination/pump", "gopkg.in/yaml.v2"}, importPaths(selected.Deps))
+
+	selected, err = snapshot.SelectDeps(depsFile, []string{"github.com/desal/git"})
+	require.Nil(t, err)
+	assert.Equal(t, []string{"github.com/desal/git"}, importPaths(selected.Deps))
+	assert.Equal(t, []string{}, importPaths(selected.TestDeps))
+
+	_, err = snapshot.SelectDeps(depsFile, []string{"github.com/desal/git", "github.com/missing/..."})
+	assert.EqualError(t, err, "No dependency matches 'github.com/missing/...'.")
+}
+
+func TestUpdateSelected(t *testing.T) {
+	m := SetupRepos(t)
+	defer m.Close()
+
+	m.AddRepo("depone")
+	m.AddRepo("deptwo")
+
+	pinned := map[string]string{}
+	latest := map[string]string{}
+	for _, repo := range []string{"depone", "deptwo"} {
+		sha, _, _ := m.goCtx.Execf("cd src/%s; git rev-parse HEAD", repo)
+		pinned[repo] = strings.TrimSpace(sha)
+		m.goCtx.Execf(`
+			cd src/%s;
+			echo 'package %s' > %s.go;
+			git add -A;
+			git commit -m "gocode";
+			git push -q;
+			git checkout -q %s`, repo, repo, repo, pinned[repo])
+		sha, _, _ = m.bareCtx.Execf("cd %s; git rev-parse master", repo)
+		latest[repo] = strings.TrimSpace(sha)
+	}
+
+	depsFile := snapshot.DepsFile{
+		Deps: []snapshot.PkgDep{
+			{ImportPath: "depone", GitRemote: dsutil.PosixPath(m.bareDir) + "/depone", SHA: pinned["depone"]},
+			{ImportPath: "deptwo", GitRemote: dsutil.PosixPath(m.bareDir) + "/deptwo", SHA: pinned["deptwo"]},
+		},
+	}
+
+	selected, err := snapshot.SelectDeps(depsFile, []string{"depone"})
+	require.Nil(t, err)
+
+	ctx := snapshot.New(richtext.Test(t), []string{m.gopath})
+	result, err := ctx.Reproduce(m.gopath, selected, true, snapshot.AlreadyExists_UpdateLatest)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(result))
+	assert.Equal(t, snapshot.Action_Pull, result[0].Action)
+
+	for repo, expected := range map[string]string{"depone": latest["depone"], "deptwo": pinned["deptwo"]} {
+		sha, _, _ := m.goCtx.Execf("cd src/%s; git rev-parse HEAD", repo)
+		assert.Equal(t, expected, strings.TrimSpace(sha), repo)
+	}
+
+	updated := snapshot.UpdateDepsFile(depsFile, result)
+	assert.Equal(t, latest["depone"], updated.Deps[0].SHA)
+	assert.Equal(t, depsFile.Deps[1], updated.Deps[1])
+
+	changes := snapshot.Changes(depsFile, result)
+	assert.Equal(t, []snapshot.ChangePkg{{ImportPath: "depone", FromSHA: pinned["depone"], ToSHA: latest["depone"]}}, changes)
+}
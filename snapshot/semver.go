@@ -0,0 +1,274 @@
+package snapshot
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//Semver is how far update may move a dependency between semver tags.
+//Semver_None pulls the latest of its branch, unless the dependency has a
+//configured constraint.
+type Semver int
+
+const (
+	Semver_None   Semver = iota
+	Semver_Patch         //Same major and minor version
+	Semver_Minor         //Same major version
+	Semver_Major         //Any newer version
+	Semver_Latest        //The highest tag, even if not tagged now or older
+)
+
+type (
+	//semver is a parsed version, with any missing minor or patch as 0.
+	semver struct {
+		major, minor, patch int
+		pre                 string
+	}
+
+	//versionBound is a single comparison in a constraint, op being one of
+	//<, <=, >, >= or =.
+	versionBound struct {
+		op      string
+		version semver
+	}
+
+	//constraint is a set of bounds a version must satisfy all of.
+	constraint []versionBound
+
+	//tagVersion is a semver tag and the revision it points to.
+	tagVersion struct {
+		tag      string
+		revision string
+		version  semver
+	}
+
+	tagVersionsByTag []tagVersion
+)
+
+func (a tagVersionsByTag) Len() int           { return len(a) }
+func (a tagVersionsByTag) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a tagVersionsByTag) Less(i, j int) bool { return a[i].tag < a[j].tag }
+
+var (
+	semverRe   = regexp.MustCompile(`^v?([0-9]+)(\.([0-9]+))?(\.([0-9]+))?(-([0-9A-Za-z.-]+))?(\+[0-9A-Za-z.-]+)?$`)
+	wildcardRe = regexp.MustCompile(`^v?([0-9]+)(\.([0-9]+))?(\.[xX*])?$`)
+	boundOpRe  = regexp.MustCompile(`^(\^|~|>=|<=|>|<|=)?\s*(.*)$`)
+)
+
+func (s Semver) apply(c *Context) {
+	c.semver = s
+}
+
+func parseSemver(s string) (semver, bool) {
+	m := semverRe.FindStringSubmatch(s)
+	if m == nil {
+		return semver{}, false
+	}
+	r := semver{pre: m[7]}
+	r.major, _ = strconv.Atoi(m[1])
+	r.minor, _ = strconv.Atoi(m[3])
+	r.patch, _ = strconv.Atoi(m[5])
+	return r, true
+}
+
+//compare returns -1, 0 or 1 as v is lower, equal to or higher than other.
+//Pre-releases are lower than their release, and compared identifier by
+//identifier, numerically where both are numbers.
+func (v semver) compare(other semver) int {
+	for _, d := range []int{v.major - other.major, v.minor - other.minor, v.patch - other.patch} {
+		if d < 0 {
+			return -1
+		} else if d > 0 {
+			return 1
+		}
+	}
+
+	if v.pre == other.pre {
+		return 0
+	} else if v.pre == "" {
+		return 1
+	} else if other.pre == "" {
+		return -1
+	}
+
+	a, b := strings.Split(v.pre, "."), strings.Split(other.pre, ".")
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] == b[i] {
+			continue
+		}
+		aNum, aErr := strconv.Atoi(a[i])
+		bNum, bErr := strconv.Atoi(b[i])
+		if aErr == nil && bErr == nil {
+			if aNum < bNum {
+				return -1
+			}
+			return 1
+		} else if aErr == nil {
+			return -1
+		} else if bErr == nil {
+			return 1
+		} else if a[i] < b[i] {
+			return -1
+		}
+		return 1
+	}
+	if len(a) < len(b) {
+		return -1
+	}
+	return 1
+}
+
+//parseConstraint parses a comma separated list of bounds, each one of:
+//  ^1.2.3  >=1.2.3 <2.0.0, or <0.3.0 below 1.0.0
+//  ~1.2.3  >=1.2.3 <1.3.0
+//  1.2.x   >=1.2.0 <1.3.0, as is 1.2
+//  >=1.2.3, >1.2.3, <=1.2.3, <1.2.3 or =1.2.3
+func parseConstraint(s string) (constraint, error) {
+	r := constraint{}
+	for _, term := range strings.Split(s, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" || term == "*" {
+			continue
+		}
+
+		m := boundOpRe.FindStringSubmatch(term)
+		op, version := m[1], strings.TrimSpace(m[2])
+
+		if op == "" || op == "=" {
+			if w := wildcardRe.FindStringSubmatch(version); w != nil {
+				//1 is >=1.0.0 <2.0.0, 1.2 or 1.2.x is >=1.2.0 <1.3.0
+				lower, _ := parseSemver(w[1] + w[2])
+				upper := semver{major: lower.major + 1}
+				if w[2] != "" {
+					upper = semver{major: lower.major, minor: lower.minor + 1}
+				}
+				r = append(r, versionBound{">=", lower}, versionBound{"<", upper})
+				continue
+			}
+		}
+
+		v, ok := parseSemver(version)
+		if !ok {
+			return nil, fmt.Errorf("Invalid version constraint '%s'.", term)
+		}
+
+		switch op {
+		case "^":
+			upper := semver{major: v.major + 1}
+			if v.major == 0 && v.minor == 0 && strings.Count(version, ".") == 2 {
+				upper = semver{patch: v.patch + 1}
+			} else if v.major == 0 && strings.Count(version, ".") >= 1 {
+				upper = semver{minor: v.minor + 1}
+			}
+			r = append(r, versionBound{">=", v}, versionBound{"<", upper})
+		case "~":
+			upper := semver{major: v.major, minor: v.minor + 1}
+			if !strings.Contains(version, ".") {
+				upper = semver{major: v.major + 1}
+			}
+			r = append(r, versionBound{">=", v}, versionBound{"<", upper})
+		case "":
+			r = append(r, versionBound{"=", v})
+		default:
+			r = append(r, versionBound{op, v})
+		}
+	}
+	return r, nil
+}
+
+func (c constraint) allows(v semver) bool {
+	for _, bound := range c {
+		d := v.compare(bound.version)
+		ok := false
+		switch bound.op {
+		case "<":
+			ok = d < 0
+		case "<=":
+			ok = d <= 0
+		case ">":
+			ok = d > 0
+		case ">=":
+			ok = d >= 0
+		case "=":
+			ok = d == 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+//currentVersion is the highest semver tag in tags.
+func currentVersion(tags []string) (semver, bool) {
+	var r semver
+	found := false
+	for _, tag := range tags {
+		if v, ok := parseSemver(tag); ok && (!found || v.compare(r) > 0) {
+			r, found = v, true
+		}
+	}
+	return r, found
+}
+
+//semverUpdate is true if update should move pkgDep between tags rather than
+//pulling its branch.
+func (c *Context) semverUpdate(pkgDep PkgDep) bool {
+	return c.semver != Semver_None || c.config.Deps[pkgDep.ImportPath].Constraint != ""
+}
+
+//selectTag picks the highest tag allowed by the Semver option and configured
+//constraint for pkgDep. It returns nil if pkgDep should be left as it is.
+func (c *Context) selectTag(pkgDep PkgDep, tags []tagVersion) (*tagVersion, error) {
+	allowed, err := parseConstraint(c.config.Deps[pkgDep.ImportPath].Constraint)
+	if err != nil {
+		return nil, err
+	}
+
+	current, hasCurrent := currentVersion(pkgDep.Tags)
+	switch c.semver {
+	case Semver_Patch, Semver_Minor, Semver_Major:
+		if !hasCurrent {
+			return nil, nil
+		}
+		allowed = append(allowed, versionBound{">=", current})
+		if c.semver == Semver_Patch {
+			allowed = append(allowed, versionBound{"<", semver{major: current.major, minor: current.minor + 1}})
+		} else if c.semver == Semver_Minor {
+			allowed = append(allowed, versionBound{"<", semver{major: current.major + 1}})
+		}
+	}
+
+	var r *tagVersion
+	for i, tag := range tags {
+		//Pre-releases only once already on one
+		if tag.version.pre != "" && (!hasCurrent || current.pre == "") {
+			continue
+		}
+		if allowed.allows(tag.version) && (r == nil || tag.version.compare(r.version) > 0) {
+			r = &tags[i]
+		}
+	}
+	return r, nil
+}
+
+//selectLocalTag picks the tag to update pkgDep to from the tags in its
+//local repository dir.
+func (c *Context) selectLocalTag(vcs VCS, dir string, pkgDep PkgDep) (*tagVersion, error) {
+	revisions, err := vcs.TagRevisions(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	r := []tagVersion{}
+	for tag, revision := range revisions {
+		if v, ok := parseSemver(tag); ok {
+			r = append(r, tagVersion{tag: tag, revision: revision, version: v})
+		}
+	}
+	sort.Sort(tagVersionsByTag(r))
+	return c.selectTag(pkgDep, r)
+}
@@ -0,0 +1,112 @@
+package snapshot_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/desal/dsutil"
+	"github.com/desal/go-snap/snapshot"
+	"github.com/desal/richtext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateSemver(t *testing.T) {
+	m := SetupRepos(t)
+	defer m.Close()
+
+	m.AddRepo("depone")
+
+	tagSHAs := map[string]string{}
+	for _, tag := range []string{"v1.0.0", "v1.0.1", "v1.1.0", "v2.0.0", "v2.1.0-beta.1"} {
+		m.goCtx.Execf(`
+			cd src/depone;
+			echo 'package depone\n\nconst Version = "%s"' > depone.go;
+			git add -A;
+			git commit -m "%s";
+			git tag -a -m "%s" %s;
+			git push -q --tags origin master`, tag, tag, tag, tag)
+		sha, _, _ := m.goCtx.Execf(`cd src/depone; git rev-parse HEAD`)
+		tagSHAs[tag] = strings.TrimSpace(sha)
+	}
+	m.goCtx.Execf(`cd src/depone; git checkout -q v1.0.0`)
+
+	pinned := snapshot.PkgDep{ImportPath: "depone", GitRemote: dsutil.PosixPath(m.bareDir) + "/depone", SHA: tagSHAs["v1.0.0"], Tags: []string{"v1.0.0"}}
+	depsFile := snapshot.DepsFile{Deps: []snapshot.PkgDep{pinned}}
+
+	constrained := func(constraint string) snapshot.Config {
+		return snapshot.Config{Deps: map[string]snapshot.DepConfig{"depone": {Constraint: constraint}}}
+	}
+
+	tests := []struct {
+		options []snapshot.Option
+		tag     string
+	}{
+		{[]snapshot.Option{snapshot.Semver_Patch}, "v1.0.1"},
+		{[]snapshot.Option{snapshot.Semver_Minor}, "v1.1.0"},
+		{[]snapshot.Option{snapshot.Semver_Major}, "v2.0.0"},
+		{[]snapshot.Option{snapshot.Semver_Latest}, "v2.0.0"},
+		{[]snapshot.Option{constrained("~1.0")}, "v1.0.1"},
+		{[]snapshot.Option{constrained("^1")}, "v1.1.0"},
+		{[]snapshot.Option{constrained(">=1.0.1, <1.1")}, "v1.0.1"},
+		{[]snapshot.Option{constrained("1.x")}, "v1.1.0"},
+		{[]snapshot.Option{snapshot.Semver_Major, constrained("<2")}, "v1.1.0"},
+		{[]snapshot.Option{constrained("^1.0.0"), snapshot.Semver_Patch}, "v1.0.1"},
+	}
+
+	for _, test := range tests {
+		ctx := snapshot.New(richtext.Test(t), []string{m.gopath}, test.options...)
+		plan := ctx.Plan(m.gopath, depsFile, true, snapshot.AlreadyExists_UpdateLatest)
+		require.Equal(t, 1, len(plan))
+		assert.Equal(t, snapshot.Action_Checkout, plan[0].Action, test.tag)
+		assert.Equal(t, test.tag, plan[0].Tag)
+		assert.Equal(t, tagSHAs[test.tag], plan[0].ToSHA, test.tag)
+	}
+
+	//Already at the highest allowed tag fetched, newer may be pulled
+	ctx := snapshot.New(richtext.Test(t), []string{m.gopath}, constrained("=1.0.0"))
+	plan := ctx.Plan(m.gopath, depsFile, true, snapshot.AlreadyExists_UpdateLatest)
+	assert.Equal(t, snapshot.Action_Pull, plan[0].Action)
+	assert.Equal(t, "", plan[0].ToSHA)
+
+	result, err := ctx.Reproduce(m.gopath, depsFile, true, snapshot.AlreadyExists_UpdateLatest)
+	require.Nil(t, err)
+	assert.Equal(t, snapshot.Action_Skip, result[0].Action)
+	sha, _, _ := m.goCtx.Execf(`cd src/depone; git rev-parse HEAD`)
+	assert.Equal(t, tagSHAs["v1.0.0"], strings.TrimSpace(sha))
+
+	ctx = snapshot.New(richtext.Test(t), []string{m.gopath}, constrained("^one"))
+	plan = ctx.Plan(m.gopath, depsFile, true, snapshot.AlreadyExists_UpdateLatest)
+	assert.Equal(t, snapshot.Action_Fail, plan[0].Action)
+
+	ctx = snapshot.New(richtext.Test(t), []string{m.gopath}, snapshot.Semver_Minor)
+	result, err = ctx.Reproduce(m.gopath, depsFile, true, snapshot.AlreadyExists_UpdateLatest)
+	require.Nil(t, err)
+	require.NotNil(t, result[0].Updated)
+	assert.Equal(t, tagSHAs["v1.1.0"], result[0].Updated.SHA)
+	assert.Equal(t, []string{"v1.1.0"}, result[0].Updated.Tags)
+	assert.NotEqual(t, "", result[0].Updated.Hash)
+
+	updated := snapshot.UpdateDepsFile(depsFile, result)
+	assert.Equal(t, *result[0].Updated, updated.Deps[0])
+	assert.Equal(t, pinned, depsFile.Deps[0])
+
+	sha, _, _ = m.goCtx.Execf(`cd src/depone; git rev-parse HEAD`)
+	assert.Equal(t, tagSHAs["v1.1.0"], strings.TrimSpace(sha))
+
+	//Tags published upstream since the checkout was last pulled
+	m.goCtx.Execf(`
+		cd src;
+		git clone -q %s/depone published;
+		cd published;
+		git tag -a -m v1.2.0 v1.2.0;
+		git push -q --tags origin`, dsutil.PosixPath(m.bareDir))
+	published, _, _ := m.goCtx.Execf(`cd src/published; git rev-parse HEAD`)
+
+	result, err = ctx.Reproduce(m.gopath, updated, true, snapshot.AlreadyExists_UpdateLatest)
+	require.Nil(t, err)
+	assert.Equal(t, snapshot.Action_Checkout, result[0].Action)
+	assert.Contains(t, result[0].Updated.Tags, "v1.2.0")
+	sha, _, _ = m.goCtx.Execf(`cd src/depone; git rev-parse HEAD`)
+	assert.Equal(t, strings.TrimSpace(published), strings.TrimSpace(sha))
+}
@@ -6,8 +6,10 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/desal/git"
@@ -31,35 +33,91 @@ type (
 		goCtx           *gocmd.Context
 		snapGitCtx      *git.Context
 		reproduceGitCtx *git.Context
+		snapVCS         vcsList
+		reproduceVCS    vcsList
 		gitFlags        []git.Flag
 		flags           flagSet
+		jobs            int
+		config          Config
+		semver          Semver
+		policy          Policy
+		ignore          []string
+		allowDirty      []string
+		platforms       Platforms
 	}
 
+	//Option configures a Context. Both Flag and the valued options such as
+	//Jobs satisfy it.
+	Option interface {
+		apply(c *Context)
+	}
+
+	//Jobs is the number of dependencies to scan or reproduce concurrently,
+	//defaults to 1.
+	Jobs int
+
+	//Ignore is import path globs, such as github.com/desal/..., of
+	//dependencies Snapshot leaves out entirely, matched against the root
+	//import path or module recorded. Unless empty it replaces any already
+	//given, such as configured.
+	Ignore []string
+
+	//AllowDirty is import path globs of dependencies Snapshot records even
+	//with uncommitted or unpushed changes, warning instead of failing. Unless
+	//empty it replaces any already given, such as configured.
+	AllowDirty []string
+
 	DepsFile struct {
 		Deps     []PkgDep
 		TestDeps []PkgDep
+		Graph    *Graph `json:",omitempty"` //Only with the ImportGraph flag
 	}
 
 	PkgDep struct {
 		ImportPath string
-		GitRemote  string //Blank for standard packages
-		SHA        string //Blank for standard packages
+		VCS        string `json:",omitempty"` //Blank for git
+		GitRemote  string //Blank for standard packages, the remote for any VCS
+		SHA        string //Blank for standard packages, the revision for any VCS
+		Branch     string `json:",omitempty"` //Followed by update, blank for the remote's default
 		CommitTime time.Time
 		Tags       []string
-		Error      error `json:"-"`
+		Hash       string  `json:",omitempty"` //Of the files tracked at SHA, see Verify
+		Module     *Module `json:",omitempty"` //Only when snapshotted as a module
+		Builds     []Build `json:",omitempty"` //Requiring it, when more than the default was listed
+		Error      error   `json:"-"`
+	}
+
+	//Module records the module providing a dependency in a module-aware
+	//project. Replace is the path or directory from a replace directive, in
+	//which case Version and Sum are those of the replacement. Unless replaced
+	//with a local directory it comes from the module cache, with no GitRemote
+	//or full SHA, so Reproduce leaves it to go mod.
+	Module struct {
+		Path    string
+		Version string `json:",omitempty"`
+		Sum     string `json:",omitempty"`
+		Replace string `json:",omitempty"`
 	}
 
 	PkgDepsByImport []PkgDep
 )
 
 const (
-	_          Flag = iota
-	MustExit        //
-	MustPanic       //
-	Warn            //
-	Verbose         // show pkgname\n as it goes
-	CmdVerbose      // Also displays commands being executed
-	SkipVendor      //
+	_           Flag = iota
+	MustExit         //
+	MustPanic        //
+	Warn             //
+	Verbose          // show pkgname\n as it goes
+	CmdVerbose       // Also displays commands being executed
+	SkipVendor       //
+	Atomic           // Roll back all changes if Reproduce fails
+	Vendor           // Reproduce into vendor/ as plain files instead of GOPATH
+	StripTests       // Leave _test.go files and testdata out of vendor/
+	StripAssets      // Leave everything but .go files and licenses out of vendor/
+	Offline          // Outdated uses GOPATH checkouts as they are, without fetching
+	Log              // Compare includes the commit log of each moved dependency
+	Quiet            // Compare and Verify only return their results, without printing
+	ImportGraph      // Snapshot records the Graph of imports between repositories
 )
 
 var (
@@ -95,24 +153,47 @@ func (a PkgDepsByImport) Len() int           { return len(a) }
 func (a PkgDepsByImport) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 func (a PkgDepsByImport) Less(i, j int) bool { return a[i].ImportPath < a[j].ImportPath }
 
-func New(format richtext.Format, goPath []string, flags ...Flag) *Context {
+func (f Flag) apply(c *Context) {
+	if gitFlag, ok := gitFlags[f]; ok {
+		c.gitFlags = append(c.gitFlags, gitFlag)
+	}
+	c.flags[f] = empty{}
+}
+
+func (j Jobs) apply(c *Context) {
+	c.jobs = int(j)
+}
+
+func (i Ignore) apply(c *Context) {
+	if len(i) != 0 {
+		c.ignore = i
+	}
+}
+
+func (a AllowDirty) apply(c *Context) {
+	if len(a) != 0 {
+		c.allowDirty = a
+	}
+}
+
+func New(format richtext.Format, goPath []string, options ...Option) *Context {
 	c := &Context{
 		doneDirs: stringSet{},
 		format:   format,
 		goPath:   goPath,
 		goCtx:    gocmd.New(format, goPath, "", ""),
 		flags:    flagSet{},
+		jobs:     1,
 	}
 
-	for _, flag := range flags {
-		if gitFlag, ok := gitFlags[flag]; ok {
-			c.gitFlags = append(c.gitFlags, gitFlag)
-		}
-		c.flags[flag] = empty{}
+	for _, option := range options {
+		option.apply(c)
 	}
 
 	c.snapGitCtx = git.New(format, c.gitFlags...)
 	c.reproduceGitCtx = git.New(format, append(c.gitFlags, git.LocalOnly)...)
+	c.snapVCS = newVCSList(c, c.snapGitCtx)
+	c.reproduceVCS = newVCSList(c, c.reproduceGitCtx)
 
 	return c
 }
@@ -141,19 +222,76 @@ func (c *Context) verbosef(s string, a ...interface{}) {
 	}
 }
 
-func ReadJson(filename string) (DepsFile, error) {
-	var result DepsFile
+//parallel calls fn once for every index in [0, n), running at most c.jobs
+//calls at a time. If done is not nil it is called from the calling goroutine
+//as each call completes. It returns once all calls have completed.
+func (c *Context) parallel(n int, fn func(i int), done func(i int)) {
+	jobs := c.jobs
+	if jobs > n {
+		jobs = n
+	}
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	indices := make(chan int)
+	completed := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				fn(i)
+				completed <- i
+			}
+		}()
+	}
+
+	go func() {
+		for i := 0; i < n; i++ {
+			indices <- i
+		}
+		close(indices)
+		wg.Wait()
+		close(completed)
+	}()
+
+	for i := range completed {
+		if done != nil {
+			done(i)
+		}
+	}
+}
 
-	var r io.Reader
-	var err error
+//openInput opens filename for reading, or stdin if it is "stdin".
+func openInput(filename string) (io.ReadCloser, error) {
 	if filename == "stdin" {
-		r = os.Stdin
+		return ioutil.NopCloser(os.Stdin), nil
+	}
+	return os.Open(filename)
+}
+
+//writeOutput writes output to filename, or stdout if it is "stdout".
+func writeOutput(filename string, output []byte) error {
+	if filename == "stdout" {
+		_, err := os.Stdout.Write(output)
+		return err
+	} else if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
 	} else {
-		r, err = os.Open(filename)
-		if err != nil {
-			return result, err
-		}
+		return ioutil.WriteFile(filename, output, 0644)
+	}
+}
+
+func ReadJson(filename string) (DepsFile, error) {
+	var result DepsFile
+
+	r, err := openInput(filename)
+	if err != nil {
+		return result, err
 	}
+	defer r.Close()
 
 	dec := json.NewDecoder(r)
 	err = dec.Decode(&result)
@@ -166,12 +304,30 @@ func WriteJson(filename string, depsFile DepsFile) error {
 		return err
 	}
 
-	if filename == "stdout" {
-		_, err := os.Stdout.Write(jsonOutput)
-		return err
-	} else {
-		return ioutil.WriteFile(filename, jsonOutput, 0644)
+	return writeOutput(filename, jsonOutput)
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 6 {
+		return sha[0:6]
+	}
+	return sha
+}
+
+func (p PkgDep) moduleVersion() string {
+	if p.Module == nil {
+		return ""
+	}
+	return p.Module.Version
+}
+
+//revision is a short description of the version of a dependency, its
+//module version if it has one, otherwise its abbreviated SHA.
+func (p PkgDep) revision() string {
+	if version := p.moduleVersion(); version != "" {
+		return version
 	}
+	return shortSHA(p.SHA)
 }
 
 func pkgContains(parent, child string) bool {
@@ -0,0 +1,88 @@
+package snapshot
+
+import (
+	"time"
+
+	"github.com/desal/git"
+)
+
+//svnVCS is Subversion. Revisions are revision numbers, and there are no
+//tags or branches as svn keeps them as directories.
+type svnVCS struct {
+	c *Context
+}
+
+func (v *svnVCS) execf(dir, format string, a ...interface{}) (string, error) {
+	return v.c.vcsExecf(dir, "svn", format, a...)
+}
+
+func (v *svnVCS) Name() string { return "svn" }
+
+func (v *svnVCS) IsRepo(dir string) bool {
+	_, err := v.execf(dir, "info")
+	return err == nil
+}
+
+func (v *svnVCS) Status(dir, branch string) (git.Status, error) {
+	status, err := v.execf(dir, "status -q")
+	if err != nil {
+		return git.Clean, err
+	} else if status != "" {
+		return git.Uncommitted, nil
+	}
+	return git.Clean, nil
+}
+
+func (v *svnVCS) Branch(dir string) (string, error) {
+	return "", nil
+}
+
+func (v *svnVCS) DefaultBranch(dir string) (string, error) {
+	return "", nil
+}
+
+func (v *svnVCS) TopLevel(dir string) (string, error) {
+	return v.execf(dir, "info --show-item wc-root")
+}
+
+func (v *svnVCS) RemoteUrl(dir string) (string, error) {
+	topLevel, err := v.TopLevel(dir)
+	if err != nil {
+		return "", err
+	}
+	return v.execf(topLevel, "info --show-item url")
+}
+
+func (v *svnVCS) Revision(dir string) (string, error) {
+	return v.execf(dir, "info --show-item revision")
+}
+
+func (v *svnVCS) CommitTime(dir string) (time.Time, error) {
+	output, err := v.execf(dir, "info --show-item last-changed-date")
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339Nano, output)
+}
+
+func (v *svnVCS) Tags(dir string) ([]string, error) {
+	return nil, nil
+}
+
+func (v *svnVCS) TagRevisions(dir string) (map[string]string, error) {
+	return map[string]string{}, nil
+}
+
+func (v *svnVCS) Clone(dir, remote string) error {
+	return v.c.cloneExecf(dir, "svn", "checkout -q '%s' '%s'", remote, dir)
+}
+
+func (v *svnVCS) Checkout(dir, revision string) error {
+	_, err := v.execf(dir, "update -q -r '%s'", revision)
+	return err
+}
+
+func (v *svnVCS) Pull(dir, branch string) error {
+	_, err := v.execf(dir, "update -q")
+	return err
+}
@@ -0,0 +1,191 @@
+package snapshot
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/desal/cmd"
+	"github.com/desal/git"
+)
+
+type (
+	//VCS is a version control system dependencies can be snapshotted from and
+	//reproduced with. A revision is whatever identifies a commit in that
+	//system, the SHA for git. Branches are blank for systems without them,
+	//and a blank branch argument means the remote's default branch.
+	VCS interface {
+		Name() string
+		IsRepo(dir string) bool
+		//Status is NotMaster when dir is not at the head of branch
+		Status(dir, branch string) (git.Status, error)
+		//Branch is the branch checked out, or the default when detached
+		Branch(dir string) (string, error)
+		DefaultBranch(dir string) (string, error)
+		TopLevel(dir string) (string, error)
+		RemoteUrl(dir string) (string, error)
+		Revision(dir string) (string, error)
+		CommitTime(dir string) (time.Time, error)
+		Tags(dir string) ([]string, error)
+		//TagRevisions maps every tag in the local repository to its revision
+		TagRevisions(dir string) (map[string]string, error)
+		Clone(dir, remote string) error
+		Checkout(dir, revision string) error
+		//Pull updates dir to the latest revision of branch
+		Pull(dir, branch string) error
+	}
+
+	//vcsList is every supported VCS, in the order they are detected.
+	vcsList []VCS
+
+	gitVCS struct {
+		c   *Context
+		ctx *git.Context
+	}
+)
+
+func newVCSList(c *Context, gitCtx *git.Context) vcsList {
+	return vcsList{&gitVCS{c, gitCtx}, &hgVCS{c}, &bzrVCS{c}, &svnVCS{c}}
+}
+
+//detect returns the VCS dir is checked out with, or nil if none.
+func (l vcsList) detect(dir string) VCS {
+	for _, vcs := range l {
+		if vcs.IsRepo(dir) {
+			return vcs
+		}
+	}
+	return nil
+}
+
+//named returns the VCS recorded in PkgDep.VCS, where blank is git, or nil
+//if it isn't supported.
+func (l vcsList) named(name string) VCS {
+	if name == "" {
+		name = "git"
+	}
+	for _, vcs := range l {
+		if vcs.Name() == name {
+			return vcs
+		}
+	}
+	return nil
+}
+
+//vcsName is the PkgDep.VCS of a dependency checked out with vcs.
+func vcsName(vcs VCS) string {
+	if vcs.Name() == "git" {
+		return ""
+	}
+	return vcs.Name()
+}
+
+//vcsExecf runs a command of a version control tool in dir, returning its
+//trimmed output.
+func (c *Context) vcsExecf(dir, tool, format string, a ...interface{}) (string, error) {
+	stdout, stderr, err := cmd.New(dir, c.format).Execf(tool+" "+format, a...)
+	if err != nil {
+		return "", fmt.Errorf("%s: %s", err.Error(), strings.TrimSpace(stderr))
+	}
+	return strings.TrimSpace(stdout), nil
+}
+
+//cloneExecf runs a command that checks out a new copy into dir from its
+//parent directory, creating the parent if necessary.
+func (c *Context) cloneExecf(dir, tool, format string, a ...interface{}) error {
+	parent := filepath.Dir(dir)
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return err
+	}
+	_, err := c.vcsExecf(parent, tool, format, a...)
+	return err
+}
+
+func (v *gitVCS) Name() string                             { return "git" }
+func (v *gitVCS) IsRepo(dir string) bool                   { return v.ctx.IsGit(dir) }
+func (v *gitVCS) TopLevel(dir string) (string, error)      { return v.ctx.TopLevel(dir) }
+func (v *gitVCS) RemoteUrl(dir string) (string, error)     { return v.ctx.RemoteOriginUrl(dir) }
+func (v *gitVCS) Revision(dir string) (string, error)      { return v.ctx.SHA(dir) }
+func (v *gitVCS) CommitTime(dir string) (time.Time, error) { return v.ctx.CommitTime(dir) }
+func (v *gitVCS) Tags(dir string) ([]string, error)        { return v.ctx.Tags(dir) }
+func (v *gitVCS) Clone(dir, remote string) error           { return v.ctx.Clone(dir, remote) }
+func (v *gitVCS) Checkout(dir, revision string) error      { return v.ctx.Checkout(dir, revision) }
+
+//Status is only trusted for uncommitted and unpushed changes, as the git
+//package compares against origin/master.
+func (v *gitVCS) Status(dir, branch string) (git.Status, error) {
+	status, err := v.ctx.Status(dir)
+	if err != nil || (status != git.Clean && status != git.NotMaster) {
+		return status, err
+	}
+
+	if branch == "" {
+		if branch, err = v.DefaultBranch(dir); err != nil {
+			return status, err
+		}
+	}
+
+	head, err := v.ctx.SHA(dir)
+	if err != nil {
+		return status, err
+	}
+	remoteHead, err := v.c.gitExecf(dir, "rev-parse -q --verify 'origin/%s'", branch)
+	if err != nil || head != remoteHead {
+		return git.NotMaster, nil
+	}
+	return git.Clean, nil
+}
+
+func (v *gitVCS) Branch(dir string) (string, error) {
+	branch, err := v.c.gitBranch(dir)
+	if err != nil || branch != "" {
+		return branch, err
+	}
+	return v.DefaultBranch(dir)
+}
+
+//DefaultBranch is the branch origin/HEAD points to, master if it isn't set.
+func (v *gitVCS) DefaultBranch(dir string) (string, error) {
+	ref, err := v.c.gitExecf(dir, "symbolic-ref -q --short refs/remotes/origin/HEAD")
+	if err != nil || ref == "" {
+		return "master", nil
+	}
+	return strings.TrimPrefix(ref, "origin/"), nil
+}
+
+//TagRevisions dereferences annotated tags to the commit they tag. Unlike
+//show-ref, for-each-ref succeeds with no output when there are no tags, so
+//any error is a real one.
+func (v *gitVCS) TagRevisions(dir string) (map[string]string, error) {
+	output, err := v.c.gitExecf(dir, "for-each-ref --format='%%(objectname) %%(*objectname) %%(refname:strip=2)' refs/tags")
+	if err != nil {
+		return nil, err
+	}
+
+	r := map[string]string{}
+	for _, line := range strings.Split(output, "\n") {
+		//0123abc 4567def v1.0.0, the second only for annotated tags
+		fields := strings.Fields(line)
+		if len(fields) == 2 {
+			r[fields[1]] = fields[0]
+		} else if len(fields) == 3 {
+			r[fields[2]] = fields[1]
+		}
+	}
+	return r, nil
+}
+
+func (v *gitVCS) Pull(dir, branch string) error {
+	if branch == "" {
+		var err error
+		if branch, err = v.DefaultBranch(dir); err != nil {
+			return err
+		}
+	}
+	if err := v.ctx.Checkout(dir, branch); err != nil {
+		return err
+	}
+	return v.ctx.Pull(dir)
+}
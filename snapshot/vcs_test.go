@@ -0,0 +1,126 @@
+package snapshot_test
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/desal/dsutil"
+	"github.com/desal/go-snap/snapshot"
+	"github.com/desal/richtext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func requireTool(t *testing.T, tools ...string) {
+	for _, tool := range tools {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s is not installed", tool)
+		}
+	}
+}
+
+//snapshotReproduceDepone snapshots mainpkg, which imports depone, then
+//removes depone and reproduces it from the snapshot.
+func snapshotReproduceDepone(t *testing.T, m *goCtx, vcs string) snapshot.PkgDep {
+	m.AddRepo("mainpkg")
+	m.goCtx.Execf(`
+		cd src/mainpkg;
+		echo '%s' > main.go;
+		git add -A;
+		git commit -m "gocode";
+		git push`, `package main
+
+import (
+	"fmt"
+	"depone"
+)
+
+func main() { fmt.Println(depone.One) }
+`)
+
+	ctx := snapshot.New(richtext.Test(t), []string{m.gopath})
+	depsFile, err := ctx.Snapshot(m.gopath, "mainpkg", []string{""})
+	require.Nil(t, err)
+	require.Equal(t, 1, len(depsFile.Deps))
+	pkgDep := depsFile.Deps[0]
+	assert.Equal(t, "depone", pkgDep.ImportPath)
+	assert.Equal(t, vcs, pkgDep.VCS)
+	assert.NotEqual(t, "", pkgDep.GitRemote)
+	assert.NotEqual(t, "", pkgDep.SHA)
+	assert.False(t, pkgDep.CommitTime.IsZero())
+
+	m.goCtx.Execf(`rm -rf src/depone`)
+
+	result, err := ctx.Reproduce(m.gopath, depsFile, true, snapshot.AlreadyExists_Fail)
+	require.Nil(t, err)
+	assert.Equal(t, []snapshot.ReproducePkg{{ImportPath: "depone", Action: snapshot.Action_Clone}}, result)
+
+	contents, _, _ := m.goCtx.Execf(`cat src/depone/depone.go`)
+	assert.Equal(t, "package depone\n\nconst One = 12\n", contents)
+
+	result, err = ctx.Reproduce(m.gopath, depsFile, true, snapshot.AlreadyExists_Check)
+	require.Nil(t, err)
+	assert.Equal(t, snapshot.Action_Skip, result[0].Action)
+	return pkgDep
+}
+
+func TestVCSMercurial(t *testing.T) {
+	requireTool(t, "hg")
+	m := SetupRepos(t)
+	defer m.Close()
+
+	m.bareCtx.Execf("hg init depone")
+	m.goCtx.Execf(`
+		cd src;
+		hg clone -q '%s/depone' depone;
+		cd depone;
+		echo 'package depone\n\nconst One = 12' > depone.go;
+		hg add -q;
+		hg commit -q -u test -m "gocode";
+		hg push -q`, dsutil.PosixPath(m.bareDir))
+
+	pkgDep := snapshotReproduceDepone(t, m, "hg")
+	assert.Equal(t, dsutil.PosixPath(m.bareDir)+"/depone", pkgDep.GitRemote)
+	assert.Equal(t, 40, len(pkgDep.SHA))
+}
+
+func TestVCSBazaar(t *testing.T) {
+	requireTool(t, "bzr")
+	m := SetupRepos(t)
+	defer m.Close()
+
+	m.bareCtx.Execf("bzr init -q depone")
+	m.goCtx.Execf(`
+		export BZR_EMAIL='test <test@example.com>';
+		cd src;
+		bzr branch -q '%s/depone' depone;
+		cd depone;
+		echo 'package depone\n\nconst One = 12' > depone.go;
+		bzr add -q;
+		bzr commit -q -m "gocode";
+		bzr tag -q v1.0;
+		bzr push -q :parent`, dsutil.PosixPath(m.bareDir))
+
+	pkgDep := snapshotReproduceDepone(t, m, "bzr")
+	assert.Equal(t, []string{"v1.0"}, pkgDep.Tags)
+}
+
+func TestVCSSubversion(t *testing.T) {
+	requireTool(t, "svn", "svnadmin")
+	m := SetupRepos(t)
+	defer m.Close()
+
+	m.bareCtx.Execf("svnadmin create depone")
+	m.goCtx.Execf(`
+		cd src;
+		svn checkout -q 'file://%s/depone' depone;
+		cd depone;
+		echo 'package depone\n\nconst One = 12' > depone.go;
+		svn add -q depone.go;
+		svn commit -q -m "gocode";
+		svn update -q`, dsutil.PosixPath(m.bareDir))
+
+	pkgDep := snapshotReproduceDepone(t, m, "svn")
+	assert.Equal(t, "file://"+dsutil.PosixPath(m.bareDir)+"/depone", pkgDep.GitRemote)
+	assert.Equal(t, "1", pkgDep.SHA)
+}
@@ -0,0 +1,147 @@
+package snapshot
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+//Files kept by StripAssets even though they aren't go source.
+var licensePrefixes = []string{"LICENSE", "LICENCE", "COPYING", "NOTICE", "PATENTS", "AUTHORS"}
+
+//vendorSource returns a git repository containing the pinned commit of
+//pkgDep, the GOPATH checkout if it has it, otherwise a fresh bare clone that
+//cleanup removes.
+func (c *Context) vendorSource(pkgDep PkgDep) (string, func(), error) {
+	goPathDir := filepath.Join(c.goPath[0], "src", pkgDep.ImportPath)
+	if c.reproduceGitCtx.IsGit(goPathDir) {
+		if _, err := c.gitExecf(goPathDir, "cat-file -e '%s^{commit}'", pkgDep.SHA); err == nil {
+			return goPathDir, func() {}, nil
+		}
+	}
+
+	tempDir, err := ioutil.TempDir("", "go-snap-vendor")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(tempDir) }
+
+	if _, err := c.gitExecf(tempDir, "clone --quiet --bare '%s' .", c.remote(pkgDep)); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return tempDir, cleanup, nil
+}
+
+//keepVendored is false for files the StripTests and StripAssets flags leave
+//out. name is of a file, slash separated and relative to the repository root.
+func (c *Context) keepVendored(name string) bool {
+	base := path.Base(name)
+	if c.flags.Checked(StripTests) {
+		if base == "testdata" || strings.HasPrefix(name, "testdata/") || strings.Contains(name, "/testdata/") {
+			return false
+		} else if strings.HasSuffix(base, "_test.go") {
+			return false
+		}
+	}
+
+	if c.flags.Checked(StripAssets) && !strings.HasSuffix(base, ".go") {
+		for _, prefix := range licensePrefixes {
+			if strings.HasPrefix(strings.ToUpper(base), prefix) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+//vendorDep replaces dir with the files of pkgDep at its pinned SHA, without
+//any git metadata. They are the files commitHash hashes, so a vendored tree
+//that isn't stripped verifies against the recorded Hash.
+func (c *Context) vendorDep(dir string, pkgDep PkgDep) error {
+	source, cleanup, err := c.vendorSource(pkgDep)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	return c.commitFiles(source, pkgDep.SHA, func(file treeFile) error {
+		if !c.keepVendored(file.name) {
+			return nil
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(file.name))
+		switch file.mode {
+		case "120000":
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			return os.Symlink(string(file.content), target)
+		case "100755":
+			return writeVendored(target, bytes.NewReader(file.content), 0755)
+		default:
+			return writeVendored(target, bytes.NewReader(file.content), 0644)
+		}
+	})
+}
+
+//saveVendored moves an existing vendored tree in dir aside, into a hidden
+//directory next to it that go ignores, returning that directory or blank if
+//there was nothing to move.
+func saveVendored(dir string) (string, error) {
+	if _, err := os.Lstat(dir); os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	saved, err := ioutil.TempDir(filepath.Dir(dir), "."+filepath.Base(dir)+"-")
+	if err != nil {
+		return "", err
+	}
+	if err := os.Rename(dir, filepath.Join(saved, "tree")); err != nil {
+		os.RemoveAll(saved)
+		return "", err
+	}
+	return saved, nil
+}
+
+//restoreVendored replaces dir with the tree saveVendored set aside in saved,
+//or just removes it if saved is blank.
+func restoreVendored(dir, saved string) error {
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	} else if saved == "" {
+		return nil
+	} else if err := os.Rename(filepath.Join(saved, "tree"), dir); err != nil {
+		return err
+	}
+	return os.RemoveAll(saved)
+}
+
+func writeVendored(target string, r io.Reader, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(f, r)
+	return err
+}
@@ -0,0 +1,162 @@
+package snapshot_test
+
+import (
+	"testing"
+
+	"github.com/desal/dsutil"
+	"github.com/desal/git"
+	"github.com/desal/go-snap/snapshot"
+	"github.com/desal/richtext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReproduceVendor(t *testing.T) {
+	m := SetupRepos(t)
+	defer m.Close()
+
+	m.AddRepo("depone")
+	m.AddRepo("deptwo")
+	m.AddRepo("mainpkg")
+
+	m.goCtx.Execf(`
+		cd src/depone;
+		echo 'package depone\n\nconst One = 12' > depone.go;
+		echo 'package depone' > depone_test.go;
+		mkdir testdata;
+		echo 'data' > testdata/data.txt;
+		echo 'MIT' > LICENSE;
+		echo 'readme' > README.md;
+		git add -A;
+		git commit -m "gocode";
+		git push`)
+
+	gitCtx := git.New(richtext.Test(t), git.MustPanic)
+	sha1, _ := gitCtx.SHA(m.bareDir + "/depone")
+
+	m.goCtx.Execf(`
+		cd src/depone;
+		echo 'package depone\n\nconst One = 24' > depone.go;
+		git add -A;
+		git commit -m "newer";
+		git push;
+		cd ..;
+		rm -rf depone`)
+	m.goCtx.Execf(`
+		cd src/deptwo;
+		echo 'package deptwo\n\nconst Two = 3' > deptwo.go;
+		git add -A;
+		git commit -m "gocode";
+		git push`)
+
+	sha2, _ := gitCtx.SHA(m.bareDir + "/deptwo")
+
+	depsFile := snapshot.DepsFile{
+		Deps: []snapshot.PkgDep{
+			snapshot.PkgDep{ImportPath: "depone", GitRemote: dsutil.PosixPath(m.bareDir) + "/depone", SHA: sha1},
+			snapshot.PkgDep{ImportPath: "deptwo", GitRemote: dsutil.PosixPath(m.bareDir) + "/missing", SHA: sha2},
+		},
+	}
+
+	ctx := snapshot.New(richtext.Test(t), []string{m.gopath}, snapshot.Vendor, snapshot.StripTests, snapshot.StripAssets, snapshot.Jobs(2))
+	result, err := ctx.Reproduce(m.gopath+"/src/mainpkg", depsFile, true, snapshot.AlreadyExists_Fail)
+	require.Nil(t, err)
+	require.Equal(t, 2, len(result))
+	assert.Equal(t, snapshot.Action_Vendor, result[0].Action)
+	assert.Equal(t, snapshot.Action_Vendor, result[1].Action)
+
+	files, _, _ := m.goCtx.Execf(`cd src/mainpkg; find vendor|sort`)
+	assert.Equal(t, `vendor
+vendor/depone
+vendor/depone/LICENSE
+vendor/depone/depone.go
+vendor/deptwo
+vendor/deptwo/deptwo.go
+`, files)
+
+	contents, _, _ := m.goCtx.Execf(`cat src/mainpkg/vendor/depone/depone.go`)
+	assert.Equal(t, "package depone\n\nconst One = 12\n", contents)
+
+	//Unstripped, and replacing what is already there
+	ctx = snapshot.New(richtext.Test(t), []string{m.gopath}, snapshot.Vendor)
+	_, err = ctx.Reproduce(m.gopath+"/src/mainpkg", depsFile, true, snapshot.AlreadyExists_Fail)
+	require.Nil(t, err)
+
+	files, _, _ = m.goCtx.Execf(`cd src/mainpkg; find vendor/depone|sort`)
+	assert.Equal(t, `vendor/depone
+vendor/depone/LICENSE
+vendor/depone/README.md
+vendor/depone/depone.go
+vendor/depone/depone_test.go
+vendor/depone/init
+vendor/depone/testdata
+vendor/depone/testdata/data.txt
+`, files)
+}
+
+func TestReproduceVendorAtomic(t *testing.T) {
+	m := SetupRepos(t)
+	defer m.Close()
+
+	m.AddRepo("depone")
+	m.AddRepo("deptwo")
+	m.AddRepo("mainpkg")
+
+	gitCtx := git.New(richtext.Test(t), git.MustPanic)
+	shas := []string{}
+	for _, one := range []string{"12", "24"} {
+		m.goCtx.Execf(`
+			cd src/depone;
+			echo 'package depone\n\nconst One = %s' > depone.go;
+			git add -A;
+			git commit -m "gocode";
+			git push`, one)
+		sha, _ := gitCtx.SHA(m.bareDir + "/depone")
+		shas = append(shas, sha)
+	}
+	m.goCtx.Execf(`
+		cd src/deptwo;
+		echo 'package deptwo\n\nconst Two = 3' > deptwo.go;
+		git add -A;
+		git commit -m "gocode";
+		git push`)
+	sha2, _ := gitCtx.SHA(m.bareDir + "/deptwo")
+
+	depsFile := func(oneSHA, twoSHA string) snapshot.DepsFile {
+		return snapshot.DepsFile{
+			Deps: []snapshot.PkgDep{
+				snapshot.PkgDep{ImportPath: "depone", GitRemote: dsutil.PosixPath(m.bareDir) + "/depone", SHA: oneSHA},
+				snapshot.PkgDep{ImportPath: "deptwo", GitRemote: dsutil.PosixPath(m.bareDir) + "/deptwo", SHA: twoSHA},
+			},
+		}
+	}
+	vendored := func() (string, string) {
+		files, _, _ := m.goCtx.Execf(`cd src/mainpkg; find vendor|sort`)
+		contents, _, _ := m.goCtx.Execf(`cat src/mainpkg/vendor/depone/depone.go`)
+		return files, contents
+	}
+	expectedFiles := "vendor\nvendor/depone\nvendor/depone/depone.go\nvendor/depone/init\nvendor/deptwo\nvendor/deptwo/deptwo.go\nvendor/deptwo/init\n"
+
+	ctx := snapshot.New(richtext.Test(t), []string{m.gopath}, snapshot.Vendor, snapshot.Atomic, snapshot.Quiet)
+	_, err := ctx.Reproduce(m.gopath+"/src/mainpkg", depsFile(shas[0], sha2), true, snapshot.AlreadyExists_Fail)
+	require.Nil(t, err)
+	files, contents := vendored()
+	assert.Equal(t, expectedFiles, files)
+	assert.Equal(t, "package depone\n\nconst One = 12\n", contents)
+
+	//deptwo fails, so depone goes back to the tree it replaced
+	result, err := ctx.Reproduce(m.gopath+"/src/mainpkg", depsFile(shas[1], "1111111111111111111111111111111111111111"), true, snapshot.AlreadyExists_Fail)
+	require.NotNil(t, err)
+	assert.True(t, result[0].RolledBack)
+	assert.Equal(t, snapshot.Action_Fail, result[1].Action)
+	files, contents = vendored()
+	assert.Equal(t, expectedFiles, files)
+	assert.Equal(t, "package depone\n\nconst One = 12\n", contents)
+
+	//Nothing set aside is left once it succeeds
+	_, err = ctx.Reproduce(m.gopath+"/src/mainpkg", depsFile(shas[1], sha2), true, snapshot.AlreadyExists_Fail)
+	require.Nil(t, err)
+	files, contents = vendored()
+	assert.Equal(t, expectedFiles, files)
+	assert.Equal(t, "package depone\n\nconst One = 24\n", contents)
+}
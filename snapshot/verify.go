@@ -0,0 +1,109 @@
+package snapshot
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/desal/dsutil"
+)
+
+//verifyDir finds the checkout of pkgDep, in workingDir/vendor with the
+//Vendor flag, otherwise the first GOPATH entry that has it.
+func (c *Context) verifyDir(workingDir string, pkgDep PkgDep) string {
+	if c.flags.Checked(Vendor) {
+		return filepath.Join(workingDir, "vendor", filepath.FromSlash(pkgDep.ImportPath))
+	}
+	return c.goPathDir(pkgDep.ImportPath)
+}
+
+//strippedHash is the hash of pkgDep as vendored with the StripTests and
+//StripAssets flags, taken from its pinned commit once that is checked against
+//hash, its recorded Hash.
+func (c *Context) strippedHash(pkgDep PkgDep, hash string) (string, error) {
+	source, cleanup, err := c.vendorSource(pkgDep)
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+
+	if full, err := c.commitHash(source, pkgDep.SHA); err != nil {
+		return "", err
+	} else if full != hash {
+		return "", fmt.Errorf("commit %s is (actual) %s", shortSHA(pkgDep.SHA), full)
+	}
+	return c.treeHash(source, pkgDep.SHA, c.keepVendored)
+}
+
+//Verify recomputes the content hash of every dependency in depsFile from the
+//files on disk, including any that git ignores, and reports those that don't
+//match the hash recorded by Snapshot. Dependencies nested inside another are
+//left out of its hash. A vendor/ tree stripped by the StripTests and
+//StripAssets flags is verified with the same flags, against the hash of the
+//pinned commit with the same files left out, which needs the commit in GOPATH
+//or from its remote.
+func (c *Context) Verify(workingDir string, depsFile DepsFile, doTests bool) ([]ComparePkg, bool) {
+	pkgDeps := append([]PkgDep{}, depsFile.Deps...)
+	if doTests {
+		pkgDeps = append(pkgDeps, depsFile.TestDeps...)
+	}
+
+	result := make([]ComparePkg, len(pkgDeps))
+	c.parallel(len(pkgDeps), func(i int) {
+		pkgDep := pkgDeps[i]
+		result[i] = ComparePkg{ImportPath: pkgDep.ImportPath}
+		if pkgDep.Hash == "" {
+			result[i].Message = "No content hash recorded"
+			result[i].CompareResult = CompareResult_Warn
+			return
+		}
+
+		//Hashes recorded by earlier versions had an h1: prefix
+		expected := strings.TrimPrefix(pkgDep.Hash, "h1:")
+		if c.flags.Checked(Vendor) && (c.flags.Checked(StripTests) || c.flags.Checked(StripAssets)) {
+			var err error
+			if expected, err = c.strippedHash(pkgDep, expected); err != nil {
+				result[i].Message = fmt.Sprintf("Failed to hash stripped %s: %s", pkgDep.ImportPath, err.Error())
+				result[i].CompareResult = CompareResult_Error
+				return
+			}
+		}
+
+		dir := c.verifyDir(workingDir, pkgDep)
+		if !dsutil.CheckPath(dir) {
+			result[i].Message = fmt.Sprintf("%s does not exist", dir)
+			result[i].CompareResult = CompareResult_Error
+			return
+		}
+
+		exclude := stringSet{}
+		for _, other := range pkgDeps {
+			if other.ImportPath != pkgDep.ImportPath && pkgContains(pkgDep.ImportPath, other.ImportPath) {
+				exclude[other.ImportPath[len(pkgDep.ImportPath)+1:]] = empty{}
+			}
+		}
+
+		hash, err := dirHash(dir, exclude)
+		if err != nil {
+			result[i].Message = fmt.Sprintf("Failed to hash %s: %s", dir, err.Error())
+			result[i].CompareResult = CompareResult_Error
+		} else if hash != expected {
+			result[i].Message = fmt.Sprintf("(expected) %s vs (actual) %s", expected, hash)
+			result[i].CompareResult = CompareResult_Error
+		}
+	}, nil)
+
+	sort.Sort(ComparePkgs(result))
+	if !c.flags.Checked(Quiet) {
+		printComparePkgs(c.format, result)
+	}
+
+	ok := true
+	for _, comparePkg := range result {
+		if comparePkg.CompareResult == CompareResult_Error {
+			ok = false
+		}
+	}
+	return result, ok
+}
@@ -0,0 +1,143 @@
+package snapshot_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/desal/go-snap/snapshot"
+	"github.com/desal/richtext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify(t *testing.T) {
+	m := SetupRepos(t)
+	defer m.Close()
+
+	m.AddRepo("depone")
+	m.AddRepo("deptwo")
+	m.AddRepo("mainpkg")
+
+	m.goCtx.Execf(`
+		cd src/depone;
+		echo 'package depone\n\nconst One = 12' > depone.go;
+		echo 'ignored.txt' > .gitignore;
+		git add -A;
+		git commit -m "gocode";
+		git push`)
+	m.goCtx.Execf(`
+		cd src/deptwo;
+		echo 'package deptwo\n\nconst Two = 3' > deptwo.go;
+		git add -A;
+		git commit -m "gocode";
+		git push`)
+	m.goCtx.Execf(`
+		cd src/mainpkg;
+		echo '%s' > main.go;
+		git add -A;
+		git commit -m "gocode";
+		git push`, `package main
+
+import (
+	"fmt"
+	"depone"
+	"deptwo"
+)
+
+func main() { fmt.Println(depone.One * deptwo.Two) }
+`)
+
+	ctx := snapshot.New(richtext.Test(t), []string{m.gopath})
+	depsFile, err := ctx.Snapshot(m.gopath, "mainpkg", []string{""})
+	require.Nil(t, err)
+	require.Equal(t, 2, len(depsFile.Deps))
+	assert.Equal(t, "81OXN4DU2jzDhPAnL11s0Q6nxDZSauIoRE/dq4sP5gg=", depsFile.Deps[1].Hash)
+
+	buf := &bytes.Buffer{}
+	ctx = snapshot.New(richtext.Debug(buf), []string{m.gopath})
+	result, ok := ctx.Verify(m.gopath+"/src/mainpkg", depsFile, true)
+	assert.True(t, ok)
+	assert.Equal(t, []snapshot.ComparePkg{
+		{ImportPath: "depone", CompareResult: snapshot.CompareResult_Ok},
+		{ImportPath: "deptwo", CompareResult: snapshot.CompareResult_Ok},
+	}, result)
+	assert.Equal(t, "[Green,None,[Bold]][ OK ][] depone \n[Green,None,[Bold]][ OK ][] deptwo \n", buf.String())
+
+	//Ignored by git, but still a change to the tree
+	m.goCtx.Execf(`echo 'extra' > src/depone/ignored.txt`)
+	ctx = snapshot.New(richtext.Test(t), []string{m.gopath})
+	result, ok = ctx.Verify(m.gopath+"/src/mainpkg", depsFile, true)
+	assert.False(t, ok)
+	assert.Equal(t, snapshot.CompareResult_Error, result[0].CompareResult)
+	assert.Contains(t, result[0].Message, "(expected) "+depsFile.Deps[0].Hash+" vs (actual) ")
+	assert.Equal(t, snapshot.CompareResult_Ok, result[1].CompareResult)
+
+	//The vendored tree has the same hash as the commit it came from
+	ctx = snapshot.New(richtext.Test(t), []string{m.gopath}, snapshot.Vendor)
+	_, err = ctx.Reproduce(m.gopath+"/src/mainpkg", depsFile, true, snapshot.AlreadyExists_Force)
+	require.Nil(t, err)
+	result, ok = ctx.Verify(m.gopath+"/src/mainpkg", depsFile, true)
+	assert.True(t, ok)
+
+	m.goCtx.Execf(`echo '//changed' >> src/mainpkg/vendor/deptwo/deptwo.go`)
+	result, ok = ctx.Verify(m.gopath+"/src/mainpkg", depsFile, true)
+	assert.False(t, ok)
+	assert.Equal(t, snapshot.CompareResult_Ok, result[0].CompareResult)
+	assert.Equal(t, snapshot.CompareResult_Error, result[1].CompareResult)
+}
+
+func TestVerifyExportIgnoreAndStripped(t *testing.T) {
+	m := SetupRepos(t)
+	defer m.Close()
+
+	m.AddRepo("depone")
+	m.AddRepo("mainpkg")
+
+	m.goCtx.Execf(`
+		cd src/depone;
+		echo 'package depone\n\nconst One = 12' > depone.go;
+		echo 'package depone' > depone_test.go;
+		echo 'readme' > README.md;
+		echo 'README.md export-ignore' > .gitattributes;
+		git add -A;
+		git commit -m "gocode";
+		git push`)
+	m.goCtx.Execf(`
+		cd src/mainpkg;
+		echo 'package main\n\nimport "depone"\n\nvar _ = depone.One' > main.go;
+		git add -A;
+		git commit -m "gocode";
+		git push`)
+
+	ctx := snapshot.New(richtext.Test(t), []string{m.gopath})
+	depsFile, err := ctx.Snapshot(m.gopath, "mainpkg", []string{""})
+	require.Nil(t, err)
+
+	//export-ignore only applies to git archive, not the checkout
+	ctx = snapshot.New(richtext.Test(t), []string{m.gopath}, snapshot.Quiet)
+	_, ok := ctx.Verify(m.gopath+"/src/mainpkg", depsFile, true)
+	assert.True(t, ok)
+
+	//As recorded before the h1: prefix was dropped
+	legacy := snapshot.DepsFile{Deps: []snapshot.PkgDep{depsFile.Deps[0]}}
+	legacy.Deps[0].Hash = "h1:" + legacy.Deps[0].Hash
+	_, ok = ctx.Verify(m.gopath+"/src/mainpkg", legacy, true)
+	assert.True(t, ok)
+
+	ctx = snapshot.New(richtext.Test(t), []string{m.gopath}, snapshot.Vendor, snapshot.StripTests, snapshot.StripAssets, snapshot.Quiet)
+	_, err = ctx.Reproduce(m.gopath+"/src/mainpkg", depsFile, true, snapshot.AlreadyExists_Force)
+	require.Nil(t, err)
+	_, ok = ctx.Verify(m.gopath+"/src/mainpkg", depsFile, true)
+	assert.True(t, ok)
+
+	m.goCtx.Execf(`echo '//changed' >> src/mainpkg/vendor/depone/depone.go`)
+	result, ok := ctx.Verify(m.gopath+"/src/mainpkg", depsFile, true)
+	assert.False(t, ok)
+	assert.Contains(t, result[0].Message, "(expected) ")
+
+	//The pinned commit itself is checked against the recorded hash
+	depsFile.Deps[0].Hash = "wrong"
+	result, ok = ctx.Verify(m.gopath+"/src/mainpkg", depsFile, true)
+	assert.False(t, ok)
+	assert.Contains(t, result[0].Message, "Failed to hash stripped depone")
+}